@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"steganografi/internal/api"
+	"steganografi/internal/jobs"
 )
 
 func main() {
@@ -21,25 +27,70 @@ func main() {
 	fs := http.FileServer(http.Dir(filepath.Join(cwd, "web", "static")))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
 
-	// Set up LSB API routes
-	http.HandleFunc("/api/lsb/encode/text", api.HandleEncodeText)
-	http.HandleFunc("/api/lsb/encode/file", api.HandleEncodeFile)
-	http.HandleFunc("/api/lsb/decode/text", api.HandleDecodeText)
-	http.HandleFunc("/api/lsb/decode/file", api.HandleDecodeFile)
+	// Set up the background job manager
+	// Jobs are kept on disk when STEG_JOBS_DIR is set, and in memory otherwise
+	var jobStore jobs.Store = jobs.NewMemoryStore()
+	if jobsDir := os.Getenv("STEG_JOBS_DIR"); jobsDir != "" {
+		diskStore, err := jobs.NewDiskStore(jobsDir)
+		if err != nil {
+			log.Fatal("Failed to open job store:", err)
+		}
+		jobStore = diskStore
+	}
+	jobManager := jobs.NewManager(jobStore, 0, 0, 0)
+	api.SetJobManager(jobManager)
+
+	// Set up LSB, BPCS, Audio and Video Steganography API routes
+	// Each method serves /encode/text, /encode/file, /decode/text and /decode/file
+	api.RegisterCarrierRoutes(http.DefaultServeMux)
+
+	// Set up Background Job API routes
+	http.HandleFunc("/api/jobs", api.HandleJobs)
+	http.HandleFunc("/api/jobs/encode", api.HandleJobEncode)
+	http.HandleFunc("/api/jobs/decode", api.HandleJobDecode)
+	http.HandleFunc("/api/jobs/{id}", api.HandleJob)
+	http.HandleFunc("/api/jobs/{id}/cancel", api.HandleJobCancel)
+	http.HandleFunc("/api/jobs/{id}/result", api.HandleJobResult)
+
+	// Set up Robust Watermark API routes
+	http.HandleFunc("/api/watermark/embed", api.HandleWatermarkEmbed)
+	http.HandleFunc("/api/watermark/extract", api.HandleWatermarkExtract)
+	http.HandleFunc("/api/watermark/test", api.HandleWatermarkTest)
+
+	// Set up Fragile Watermark API routes
+	http.HandleFunc("/api/fragile/embed", api.HandleFragileEmbed)
+	http.HandleFunc("/api/fragile/verify", api.HandleFragileVerify)
+
+	// Set up Deniable Encoding API routes
+	http.HandleFunc("/api/deniable/encode", api.HandleDeniableEncode)
+	http.HandleFunc("/api/deniable/decode", api.HandleDeniableDecode)
+
+	// Set up Multi-Carrier API routes
+	http.HandleFunc("/api/split/encode", api.HandleSplitEncode)
+	http.HandleFunc("/api/split/decode", api.HandleSplitDecode)
 
-	// Set up BPCS API routes
-	http.HandleFunc("/api/bpcs/encode/text", api.HandleBPCSEncodeText)
-	http.HandleFunc("/api/bpcs/encode/file", api.HandleBPCSEncodeFile)
-	http.HandleFunc("/api/bpcs/decode/text", api.HandleBPCSDecodeText)
-	http.HandleFunc("/api/bpcs/decode/file", api.HandleBPCSDecodeFile)
+	// Set up Batch API routes
+	http.HandleFunc("/api/batch/encode", api.HandleBatchEncode)
+	http.HandleFunc("/api/batch/decode", api.HandleBatchDecode)
 
-	// Set up Audio Steganography API routes
-	http.HandleFunc("/api/audio/encode/text", api.HandleAudioEncodeText)
-	http.HandleFunc("/api/audio/decode/text", api.HandleAudioDecodeText)
+	// Set up Secret Sharing API routes
+	http.HandleFunc("/api/share/encode", api.HandleShareEncode)
+	http.HandleFunc("/api/share/decode", api.HandleShareDecode)
 
-	// Set up Video Steganography API routes
-	http.HandleFunc("/api/video/encode/text", api.HandleVideoEncodeText)
-	http.HandleFunc("/api/video/decode/text", api.HandleVideoDecodeText)
+	// Set up Capacity and Planning API routes
+	http.HandleFunc("/api/capacity", api.HandleCapacity)
+	http.HandleFunc("/api/plan", api.HandlePlan)
+
+	// Set up Steganalysis API routes
+	http.HandleFunc("/api/analyze", api.HandleAnalyze)
+	http.HandleFunc("/api/analyze/chisquare", api.HandleChiSquareAnalyze)
+	http.HandleFunc("/api/analyze/bitplane", api.HandleBitPlane)
+	http.HandleFunc("/api/analyze/complexity", api.HandleComplexityMap)
+	http.HandleFunc("/api/analyze/audio", api.HandleAudioAnalyze)
+
+	// Set up Quality Metrics API routes
+	http.HandleFunc("/api/compare", api.HandleCompare)
+	http.HandleFunc("/api/compare/audio", api.HandleAudioCompare)
 
 	// Serve the main HTML page
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -62,6 +113,26 @@ func main() {
 
 	// Start the server
 	port := "8080"
-	fmt.Printf("Server starting on http://localhost:%s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	server := &http.Server{Addr: ":" + port}
+	go func() {
+		fmt.Printf("Server starting on http://localhost:%s\n", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Shut down on SIGINT or SIGTERM, giving requests and running jobs time to stop
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	fmt.Println("Server shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Failed to shut down the server:", err)
+	}
+	if err := jobManager.Shutdown(ctx); err != nil {
+		log.Println("Failed to shut down the job manager:", err)
+	}
 }
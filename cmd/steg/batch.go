@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"steganografi/internal/steganography"
+)
+
+// batchFlags are the flags shared by the batch-encode and batch-decode commands
+type batchFlags struct {
+	methodFlags
+	dir      string
+	out      string
+	manifest string
+	workers  int
+}
+
+// register adds the batch flags to a flag set
+func (b *batchFlags) register(flags *flag.FlagSet) {
+	b.methodFlags.register(flags)
+	flags.StringVar(&b.dir, "dir", "", "directory of carriers, searched recursively")
+	flags.StringVar(&b.out, "out", "", "output directory")
+	flags.StringVar(&b.manifest, "manifest", "", "JSON manifest with per-carrier payloads and methods")
+	flags.IntVar(&b.workers, "workers", 0, "carriers processed at once (default: number of CPUs)")
+}
+
+// load checks the flags and reads the carriers and the manifest
+func (b *batchFlags) load() ([]steganography.BatchCarrier, *steganography.BatchManifest, error) {
+	if b.dir == "" || b.out == "" {
+		return nil, nil, errors.New("both -dir and -out are required")
+	}
+	if b.mode != "" {
+		return nil, nil, errors.New("-mode is not supported in batches")
+	}
+
+	carriers, err := directoryCarriers(b.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest := &steganography.BatchManifest{}
+	if b.manifest != "" {
+		data, err := os.ReadFile(b.manifest)
+		if err != nil {
+			return nil, nil, err
+		}
+		manifest, err = steganography.ParseBatchManifest(data)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The method flags set the default method
+	if b.method != "" {
+		manifest.Method, err = b.resolve("")
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return carriers, manifest, nil
+}
+
+// options returns the batch options from the flags
+func (b *batchFlags) options() steganography.BatchOptions {
+	return steganography.BatchOptions{
+		Seed:     b.seed,
+		Password: b.password,
+		Workers:  b.workers,
+	}
+}
+
+// runBatchEncode hides payloads in every carrier of a directory
+func runBatchEncode(args []string) error {
+	flags := flag.NewFlagSet("batch-encode", flag.ExitOnError)
+	var b batchFlags
+	b.register(flags)
+	message := flags.String("message", "", "text message to hide in carriers without a manifest entry")
+	filePath := flags.String("file", "", "file to hide in carriers without a manifest entry")
+	flags.Parse(args)
+
+	carriers, manifest, err := b.load()
+	if err != nil {
+		return err
+	}
+	if *message != "" {
+		manifest.Message, manifest.File = *message, ""
+	}
+	if *filePath != "" {
+		manifest.File, manifest.Message = *filePath, ""
+	}
+
+	// Payload files are relative to the manifest, or to the working directory
+	baseDir := "."
+	if b.manifest != "" && *filePath == "" {
+		baseDir = filepath.Dir(b.manifest)
+	}
+	payloadPath := func(name string) (string, bool) {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(baseDir, filepath.FromSlash(name))
+		}
+		info, err := os.Stat(name)
+		return name, err == nil && info.Mode().IsRegular()
+	}
+
+	report := steganography.NewBatchEncodeJob(manifest, carriers, payloadPath, b.out).Encode(b.options())
+	return printBatchReport(report)
+}
+
+// runBatchDecode recovers the hidden files from every carrier of a directory
+func runBatchDecode(args []string) error {
+	flags := flag.NewFlagSet("batch-decode", flag.ExitOnError)
+	var b batchFlags
+	b.register(flags)
+	flags.Parse(args)
+
+	carriers, manifest, err := b.load()
+	if err != nil {
+		return err
+	}
+
+	report := steganography.NewBatchDecodeJob(manifest, carriers).Decode(b.options(), b.out)
+	return printBatchReport(report)
+}
+
+// Helper functions
+
+// directoryCarriers lists the files under a directory, skipping hidden files and directories
+func directoryCarriers(dir string) ([]steganography.BatchCarrier, error) {
+	var carriers []steganography.BatchCarrier
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		carriers = append(carriers, steganography.BatchCarrier{Name: filepath.ToSlash(name), Path: path})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(carriers) == 0 {
+		return nil, errors.New("no carriers in " + dir)
+	}
+	return carriers, nil
+}
+
+// printBatchReport prints a batch report and fails if any carrier failed
+func printBatchReport(report *steganography.BatchReport) error {
+	if err := printJSON(report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return errors.New(strconv.Itoa(report.Failed) + " of " + strconv.Itoa(report.Total) + " carriers failed")
+	}
+	return nil
+}
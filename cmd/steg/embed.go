@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"steganografi/internal/steganography"
+)
+
+// runEncode hides a payload in a carrier
+func runEncode(args []string) error {
+	fs := flag.NewFlagSet("encode", flag.ExitOnError)
+	var m methodFlags
+	m.register(fs)
+	in := fs.String("in", "", "carrier file, or - for stdin")
+	out := fs.String("out", "-", "stego output file, or - for stdout")
+	format := fs.String("format", "", "carrier extension when reading it from stdin")
+	message := fs.String("message", "", "text message to hide")
+	filePath := fs.String("file", "", "file to hide together with its name")
+	fs.Parse(args)
+
+	// Read the payload
+	var payload []byte
+	var err error
+	switch {
+	case *message != "" && *filePath != "":
+		return errors.New("use either -message or -file")
+	case *message != "":
+		payload = []byte(*message)
+	case *filePath != "":
+		payload, err = steganography.PrepareFileData(*filePath, filepath.Base(*filePath))
+	case *in == "-":
+		return errors.New("the carrier is read from stdin; give the payload with -message or -file")
+	default:
+		payload, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	inputPath, cleanup, err := carrierPath(*in, *format)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	encoder, err := m.encoder(inputPath)
+	if err != nil {
+		return err
+	}
+
+	// Encrypt the payload if a password is set
+	if m.encrypts(encoder) {
+		payload, err = steganography.EncryptData(payload, m.password)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Encode to a temporary file, so the encoder sees the carrier's extension
+	outputPath := tempPath("steg_output_", filepath.Ext(inputPath))
+	defer os.Remove(outputPath) // Clean up
+	if err := encoder.EncodeData(inputPath, outputPath, payload); err != nil {
+		return err
+	}
+
+	return copyOutput(*out, outputPath)
+}
+
+// runDecode recovers a payload from a carrier
+func runDecode(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	var m methodFlags
+	m.register(fs)
+	in := fs.String("in", "", "stego file, or - for stdin")
+	out := fs.String("out", "", "output file, or - for stdout (default stdout, or the hidden file's name with -file)")
+	format := fs.String("format", "", "carrier extension when reading it from stdin")
+	isFile := fs.Bool("file", false, "the payload is a file hidden with its name")
+	partial := fs.Bool("partial", false, "recover what survives of a video-frames payload, zero-filling missing segments")
+	fs.Parse(args)
+
+	inputPath, cleanup, err := carrierPath(*in, *format)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	encoder, err := m.encoder(inputPath)
+	if err != nil {
+		return err
+	}
+
+	// Decode the payload
+	var data []byte
+	if *partial {
+		frames, ok := encoder.(*steganography.VideoFrameEncoder)
+		if !ok {
+			return errors.New("-partial only applies to the video-frames methods")
+		}
+		recovery, err := frames.DecodePartial(inputPath)
+		if err != nil {
+			return err
+		}
+		if len(recovery.MissingSegments) > 0 {
+			fmt.Fprintf(os.Stderr, "Missing %d of %d segments: %v\n", len(recovery.MissingSegments), recovery.SegmentCount, recovery.MissingSegments)
+		}
+		data = recovery.Data
+	} else {
+		data, err = encoder.DecodeData(inputPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Decrypt the payload if a password is set
+	if m.encrypts(encoder) {
+		data, err = steganography.DecryptData(data, m.password)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !*isFile {
+		return writeOutput(*out, data)
+	}
+
+	// Unpack the file and its name
+	metadata, fileData, err := steganography.ExtractFileData(data)
+	if err != nil {
+		return err
+	}
+	outputPath := *out
+	if outputPath == "" {
+		outputPath = metadata.SafeFileName()
+	}
+	fmt.Fprintln(os.Stderr, "Recovered "+metadata.FileName)
+
+	return writeOutput(outputPath, fileData)
+}
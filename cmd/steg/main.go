@@ -0,0 +1,229 @@
+// Command steg hides and recovers data in images, audio and video from the command line.
+//
+// Usage:
+//
+//	steg encode   -in cover.png -out stego.png -message "hello"
+//	steg decode   -in stego.png > message.txt
+//	steg capacity -in cover.png
+//	steg analyze  -in suspect.png
+//	steg compare  -cover cover.png -stego stego.png
+//	steg batch-encode -dir covers -out stego -manifest payloads.json
+//	steg batch-decode -dir stego -out recovered
+//
+// Payloads are read from stdin and outputs written to stdout unless a flag names a file.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"steganografi/internal/steganography"
+)
+
+// command is a subcommand and its one-line description
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"encode", "hide a message, file or stdin in a carrier", runEncode},
+	{"decode", "recover hidden data from a carrier", runDecode},
+	{"capacity", "report how much data a carrier can hold", runCapacity},
+	{"analyze", "run steganalysis on an image or audio file", runAnalyze},
+	{"compare", "measure the distortion between a cover and a stego file", runCompare},
+	{"batch-encode", "hide payloads in every carrier of a directory", runBatchEncode},
+	{"batch-decode", "recover hidden files from every carrier of a directory", runBatchDecode},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "steg "+cmd.name+": "+err.Error())
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	if os.Args[1] != "help" && os.Args[1] != "-h" && os.Args[1] != "--help" {
+		fmt.Fprintln(os.Stderr, "steg: unknown command "+strconv.Quote(os.Args[1]))
+	}
+	usage()
+	os.Exit(2)
+}
+
+// usage prints the list of subcommands
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: steg <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-13s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'steg <command> -h' for the flags of a command.")
+}
+
+// methodFlags are the method settings shared by the encode, decode and capacity commands
+type methodFlags struct {
+	method    string
+	seed      string
+	threshold float64
+	plane     string
+	bitDepth  int
+	password  string
+	mode      string
+}
+
+// register adds the method flags to a flag set
+func (m *methodFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&m.method, "method", "", "lsb, bpcs, wav, avi, video-frames-lsb, video-frames-bpcs or yuv (default: by carrier extension)")
+	fs.StringVar(&m.seed, "seed", "", "seed that selects the embedding positions")
+	fs.Float64Var(&m.threshold, "threshold", 0, "BPCS complexity threshold (0.3 to 0.49, default 0.45)")
+	fs.StringVar(&m.plane, "plane", "", "YUV plane: y, u, v or all")
+	fs.IntVar(&m.bitDepth, "bitdepth", 0, "YUV bits per sample")
+	fs.StringVar(&m.password, "password", "", "encrypt the payload with AES-GCM")
+	fs.StringVar(&m.mode, "mode", "", "audio storage mode: lsb, info or padding (info and padding need -password)")
+}
+
+// encoder creates the encoder for a carrier from the method flags
+func (m *methodFlags) encoder(carrierPath string) (steganography.Embedder, error) {
+	method, err := m.resolve(carrierPath)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := steganography.NewEncoder(m.seed, method)
+	if err != nil {
+		return nil, err
+	}
+
+	// Audio has its own password and storage mode settings
+	if audio, ok := encoder.(*steganography.AudioEncoder); ok {
+		mode, err := steganography.ParseAudioMode(m.mode)
+		if err != nil {
+			return nil, err
+		}
+		audio.Mode = mode
+		audio.Password = m.password
+	} else if m.mode != "" {
+		return nil, errors.New("-mode only applies to audio carriers")
+	}
+
+	return encoder, nil
+}
+
+// resolve turns the method flags into a method, picking the default for the carrier's
+// extension when no method is given
+func (m *methodFlags) resolve(carrierPath string) (steganography.Method, error) {
+	var method steganography.Method
+	switch m.method {
+	case "":
+		methods := steganography.CarrierMethods(carrierPath)
+		if len(methods) == 0 {
+			return method, errors.New("unsupported carrier type " + strconv.Quote(filepath.Ext(carrierPath)) + "; use -method or -format")
+		}
+		method = methods[0]
+	case "wav":
+		method.Name = steganography.MethodAudioLSB
+	case "avi":
+		method.Name = steganography.MethodVideoBytes
+	default:
+		method.Name = m.method
+	}
+
+	if m.threshold != 0 {
+		method.ComplexityThreshold = m.threshold
+	}
+	if m.plane != "" {
+		method.Plane = m.plane
+	}
+	if m.bitDepth != 0 {
+		method.BitDepth = m.bitDepth
+	}
+	return method, nil
+}
+
+// encrypts reports whether the CLI encrypts the payload itself; audio encoders do it internally
+func (m *methodFlags) encrypts(encoder steganography.Embedder) bool {
+	_, isAudio := encoder.(*steganography.AudioEncoder)
+	return m.password != "" && !isAudio
+}
+
+// Helper functions
+
+// carrierPath returns a readable path for a carrier argument. A carrier of "-" is
+// read from stdin into a temporary file with the extension given by format.
+// The returned cleanup function removes any temporary file.
+func carrierPath(in, format string) (string, func(), error) {
+	if in == "" {
+		return "", nil, errors.New("no carrier given; use -in")
+	}
+	if in != "-" {
+		return in, func() {}, nil
+	}
+	if format == "" {
+		return "", nil, errors.New("reading a carrier from stdin needs -format, e.g. -format png")
+	}
+
+	path := tempPath("steg_input_", "."+strings.TrimPrefix(format, "."))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+
+	cleanup := func() { os.Remove(path) }
+	if _, err := io.Copy(file, os.Stdin); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
+
+// tempPath returns a unique path in the temporary directory
+func tempPath(prefix, ext string) string {
+	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return filepath.Join(os.TempDir(), prefix+timestamp+ext)
+}
+
+// writeOutput writes data to a file, or to stdout when path is "-" or empty
+func writeOutput(path string, data []byte) error {
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// copyOutput copies a file to another file, or to stdout when path is "-" or empty
+func copyOutput(path, srcPath string) error {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+	return writeOutput(path, data)
+}
+
+// printJSON writes a report to stdout as indented JSON
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"image"
+	_ "image/jpeg" // Register the JPEG decoder
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"steganografi/internal/steganography"
+)
+
+// capacityReport is printed by the capacity command for each method
+type capacityReport struct {
+	Method    steganography.Method `json:"method"`
+	Capacity  int                  `json:"capacity,omitempty"` // Bytes of payload
+	Unlimited bool                 `json:"unlimited,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// runCapacity reports the capacity of a carrier for one method, or for every
+// method that applies to it when -method is not given
+func runCapacity(args []string) error {
+	fs := flag.NewFlagSet("capacity", flag.ExitOnError)
+	var m methodFlags
+	m.register(fs)
+	in := fs.String("in", "", "carrier file, or - for stdin")
+	format := fs.String("format", "", "carrier extension when reading it from stdin")
+	fs.Parse(args)
+
+	inputPath, cleanup, err := carrierPath(*in, *format)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Collect the methods to report
+	var methods []steganography.Method
+	if m.method != "" {
+		method, err := m.resolve(inputPath)
+		if err != nil {
+			return err
+		}
+		methods = append(methods, method)
+	} else {
+		methods = steganography.CarrierMethods(inputPath)
+		if len(methods) == 0 {
+			return errors.New("unsupported carrier type " + filepath.Ext(inputPath))
+		}
+	}
+
+	var reports []capacityReport
+	for _, method := range methods {
+		report := capacityReport{Method: method}
+		capacity, err := methodCapacity(m, method, inputPath)
+		switch {
+		case err != nil:
+			report.Error = err.Error()
+		case capacity == steganography.UnlimitedCapacity:
+			report.Unlimited = true
+		default:
+			report.Capacity = capacity
+		}
+		reports = append(reports, report)
+	}
+
+	return printJSON(reports)
+}
+
+// methodCapacity returns the capacity of a carrier for a method with the shared flags
+func methodCapacity(m methodFlags, method steganography.Method, inputPath string) (int, error) {
+	m.method = method.Name
+	m.threshold, m.plane, m.bitDepth = method.ComplexityThreshold, method.Plane, method.BitDepth
+
+	encoder, err := m.encoder(inputPath)
+	if err != nil {
+		return 0, err
+	}
+	estimator, ok := encoder.(steganography.CapacityEstimator)
+	if !ok {
+		return 0, errors.New("method " + method.Name + " cannot report its capacity")
+	}
+
+	capacity, err := estimator.Capacity(inputPath)
+	if err != nil {
+		return 0, err
+	}
+
+	// Account for encryption done by the CLI
+	if m.encrypts(encoder) && capacity != steganography.UnlimitedCapacity {
+		capacity = max(capacity-steganography.EncryptionOverhead, 0)
+	}
+	return capacity, nil
+}
+
+// imageAnalysis is printed by the analyze command for images
+type imageAnalysis struct {
+	ChiSquare *steganography.ChiSquareResult   `json:"chiSquare"`
+	Estimate  *steganography.EmbeddingEstimate `json:"estimate"`
+}
+
+// runAnalyze runs the chi-square attack and the RS and SPA rate estimates on an
+// image, or the LSB noise analysis on an audio file
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	in := fs.String("in", "", "image or audio file, or - for stdin")
+	format := fs.String("format", "", "file extension when reading it from stdin")
+	fs.Parse(args)
+
+	inputPath, cleanup, err := carrierPath(*in, *format)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if isAudioPath(inputPath) {
+		result, err := steganography.AnalyzeAudio(inputPath)
+		if err != nil {
+			return err
+		}
+		return printJSON(result)
+	}
+
+	img, err := decodeImage(inputPath)
+	if err != nil {
+		return err
+	}
+	return printJSON(imageAnalysis{
+		ChiSquare: steganography.ChiSquareAnalyze(img),
+		Estimate:  steganography.EstimateEmbeddingRate(img),
+	})
+}
+
+// runCompare reports the distortion between a cover and a stego file, and can
+// write an amplified difference image for images
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	cover := fs.String("cover", "", "original file")
+	stego := fs.String("stego", "", "file with hidden data")
+	diff := fs.String("diff", "", "write an amplified difference PNG of two images to this file, or - for stdout")
+	amplify := fs.Int("amplify", 64, "difference amplification for -diff")
+	fs.Parse(args)
+
+	if *cover == "" || *stego == "" {
+		return errors.New("both -cover and -stego are required")
+	}
+
+	if isAudioPath(*cover) {
+		if *diff != "" {
+			return errors.New("-diff only applies to images")
+		}
+		metrics, err := steganography.CompareAudioFiles(*cover, *stego)
+		if err != nil {
+			return err
+		}
+		return printJSON(metrics)
+	}
+
+	if *diff != "" {
+		return writeDifference(*cover, *stego, *diff, *amplify)
+	}
+
+	metrics, err := steganography.CompareImageFiles(*cover, *stego)
+	if err != nil {
+		return err
+	}
+	return printJSON(metrics)
+}
+
+// writeDifference writes the amplified difference image of two image files
+func writeDifference(coverPath, stegoPath, outputPath string, amplify int) error {
+	coverImg, err := decodeImage(coverPath)
+	if err != nil {
+		return err
+	}
+	stegoImg, err := decodeImage(stegoPath)
+	if err != nil {
+		return err
+	}
+
+	diffImg, err := steganography.DifferenceImage(coverImg, stegoImg, amplify)
+	if err != nil {
+		return err
+	}
+
+	output := os.Stdout
+	if outputPath != "-" {
+		output, err = os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		defer output.Close()
+	}
+	return png.Encode(output, diffImg)
+}
+
+// Helper functions
+
+// isAudioPath reports whether a path has an audio carrier extension
+func isAudioPath(path string) bool {
+	methods := steganography.CarrierMethods(path)
+	return len(methods) > 0 && methods[0].Name == steganography.MethodAudioLSB
+}
+
+// decodeImage opens and decodes an image file
+func decodeImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	return img, err
+}
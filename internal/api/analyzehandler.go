@@ -0,0 +1,219 @@
+package api
+
+import (
+	"image"
+	"image/png"
+	"net/http"
+	"os"
+	"strconv"
+
+	"steganografi/internal/steganography"
+)
+
+// HandleChiSquareAnalyze runs the chi-square LSB attack on an uploaded image
+func HandleChiSquareAnalyze(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and decode the image
+	img, ok := imageCarrier.parseImage(w, r)
+	if !ok {
+		return
+	}
+
+	// Run the analysis
+	result := steganography.ChiSquareAnalyze(img)
+
+	// Send the response
+	sendSuccessResponse(w, "Analysis completed successfully", result)
+}
+
+// HandleAnalyze estimates the LSB embedding rate of an uploaded image with RS and SPA
+func HandleAnalyze(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and decode the image
+	img, ok := imageCarrier.parseImage(w, r)
+	if !ok {
+		return
+	}
+
+	// Run the analysis
+	result := steganography.EstimateEmbeddingRate(img)
+
+	// Send the response
+	sendSuccessResponse(w, "Analysis completed successfully", result)
+}
+
+// HandleAudioAnalyze checks an uploaded WAV or AIFF file for LSB embedding
+func HandleAudioAnalyze(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the audio file
+	carrier, ok := audioCarrier.parseUpload(w, r, "analyze_")
+	if !ok {
+		return
+	}
+	defer os.Remove(carrier.inputPath) // Clean up
+
+	// Run the analysis
+	result, err := steganography.AnalyzeAudio(carrier.inputPath)
+	if err != nil {
+		sendErrorResponse(w, "Failed to analyze audio: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Send the response
+	sendSuccessResponse(w, "Analysis completed successfully", result)
+}
+
+// HandleCompare computes quality metrics between two uploaded images
+// With "output" set to "diff" it returns an amplified difference image instead
+func HandleCompare(w http.ResponseWriter, r *http.Request) {
+	// Parse multipart form
+	if !imageCarrier.parseForm(w, r) {
+		return
+	}
+
+	// Get the images from the form
+	cover, ok := imageCarrier.formImage(w, r, "cover")
+	if !ok {
+		return
+	}
+	stego, ok := imageCarrier.formImage(w, r, "stego")
+	if !ok {
+		return
+	}
+
+	if r.FormValue("output") == "diff" {
+		// Get form values
+		amplify := 64 // Default value: makes single-step changes clearly visible
+		if amplifyStr := r.FormValue("amplify"); amplifyStr != "" {
+			var err error
+			amplify, err = strconv.Atoi(amplifyStr)
+			if err != nil || amplify < 1 || amplify > 255 {
+				amplify = 64 // Default if invalid
+			}
+		}
+
+		// Render the difference
+		diff, err := steganography.DifferenceImage(cover, stego, amplify)
+		if err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sendPNG(w, diff, "difference.png")
+		return
+	}
+
+	// Compute the metrics
+	metrics, err := steganography.CompareImages(cover, stego)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Send the response
+	sendSuccessResponse(w, "Comparison completed successfully", metrics)
+}
+
+// HandleAudioCompare computes distortion metrics between two uploaded audio files
+func HandleAudioCompare(w http.ResponseWriter, r *http.Request) {
+	// Parse multipart form
+	if !audioCarrier.parseForm(w, r) {
+		return
+	}
+
+	// Save the uploaded audio files
+	cover, ok := audioCarrier.saveFile(w, r, "cover", "cover_")
+	if !ok {
+		return
+	}
+	defer os.Remove(cover.inputPath) // Clean up
+
+	stego, ok := audioCarrier.saveFile(w, r, "stego", "stego_")
+	if !ok {
+		return
+	}
+	defer os.Remove(stego.inputPath) // Clean up
+
+	// Compute the metrics
+	metrics, err := steganography.CompareAudioFiles(cover.inputPath, stego.inputPath)
+	if err != nil {
+		sendErrorResponse(w, "Failed to compare audio: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Send the response
+	sendSuccessResponse(w, "Comparison completed successfully", metrics)
+}
+
+// HandleBitPlane renders a bit plane of an uploaded image as a PNG
+func HandleBitPlane(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and decode the image
+	img, ok := imageCarrier.parseImage(w, r)
+	if !ok {
+		return
+	}
+
+	// Get form values
+	plane, ok := formPlane(w, r)
+	if !ok {
+		return
+	}
+
+	// Render the plane
+	rendering, err := steganography.RenderBitPlane(img, r.FormValue("channel"), plane)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sendPNG(w, rendering, "bitplane.png")
+}
+
+// HandleComplexityMap renders the BPCS complexity heatmap of an uploaded image as a PNG
+func HandleComplexityMap(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and decode the image
+	img, ok := imageCarrier.parseImage(w, r)
+	if !ok {
+		return
+	}
+
+	// Get form values
+	channel := r.FormValue("channel")
+	if channel == "" {
+		channel = "red"
+	}
+	plane, ok := formPlane(w, r)
+	if !ok {
+		return
+	}
+	complexityThreshold := formComplexityThreshold(r)
+
+	// Render the heatmap
+	rendering, err := steganography.RenderComplexityMap(img, channel, plane, complexityThreshold)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sendPNG(w, rendering, "complexity.png")
+}
+
+// formPlane parses the optional "plane" form field, defaulting to the LSB plane
+// It sends an error response and returns false on failure
+func formPlane(w http.ResponseWriter, r *http.Request) (int, bool) {
+	planeStr := r.FormValue("plane")
+	if planeStr == "" {
+		return 0, true
+	}
+
+	plane, err := strconv.Atoi(planeStr)
+	if err != nil {
+		sendErrorResponse(w, "Invalid bit plane", http.StatusBadRequest)
+		return 0, false
+	}
+	return plane, true
+}
+
+// sendPNG writes an image to the response as an inline PNG
+func sendPNG(w http.ResponseWriter, img image.Image, fileName string) {
+	w.Header().Set("Content-Disposition", "inline; filename="+fileName)
+	w.Header().Set("Content-Type", "image/png")
+	png.Encode(w, img)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"steganografi/internal/steganography"
+)
+
+// batchReportName is the name of the report in a batch output archive
+const batchReportName = "report.json"
+
+// ExtractBatchArchive extracts the files of a ZIP archive into dir as batch carriers,
+// skipping directories, hidden files and the report.json of a batch output archive.
+// It stops with an error once the extracted files exceed maxBytes.
+func ExtractBatchArchive(archivePath, dir string, maxBytes int64) ([]steganography.BatchCarrier, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, errors.New("invalid ZIP archive: " + err.Error())
+	}
+	defer reader.Close()
+
+	var carriers []steganography.BatchCarrier
+	remaining := maxBytes
+	for i, file := range reader.File {
+		name := steganography.CleanBatchName(file.Name)
+		if file.FileInfo().IsDir() || isHiddenName(name) || name == batchReportName {
+			continue
+		}
+
+		// Save under the entry's index, so names cannot escape the directory
+		carrierPath := filepath.Join(dir, strconv.Itoa(i)+strings.ToLower(path.Ext(name)))
+		written, err := extractZipEntry(file, carrierPath, remaining)
+		if err != nil {
+			return carriers, err
+		}
+		remaining -= written
+		carriers = append(carriers, steganography.BatchCarrier{Name: name, Path: carrierPath})
+	}
+
+	if len(carriers) == 0 {
+		return nil, errors.New("the archive contains no files")
+	}
+	return carriers, nil
+}
+
+// WriteBatchArchive writes the outputs of the successful items in outputDir and a
+// report.json to a ZIP archive
+func WriteBatchArchive(w io.Writer, report *steganography.BatchReport, outputDir string) error {
+	zipWriter := zip.NewWriter(w)
+	for _, item := range report.Items {
+		if item.Output == "" {
+			continue
+		}
+		if err := addFileToZip(zipWriter, item.Output, filepath.Join(outputDir, filepath.FromSlash(item.Output))); err != nil {
+			return err
+		}
+	}
+
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	entry, err := zipWriter.Create(batchReportName)
+	if err != nil {
+		return err
+	}
+	if _, err := entry.Write(reportJSON); err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+// extractZipEntry copies a ZIP entry to a file, failing if it holds more than maxBytes
+func extractZipEntry(file *zip.File, outputPath string, maxBytes int64) (int64, error) {
+	entry, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer entry.Close()
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return 0, err
+	}
+	defer output.Close()
+
+	written, err := io.Copy(output, io.LimitReader(entry, maxBytes+1))
+	if err != nil {
+		return written, err
+	}
+	if written > maxBytes {
+		return written, errors.New("the archive is too large when extracted")
+	}
+	return written, nil
+}
+
+// isHiddenName reports whether any element of a path is hidden, such as the
+// __MACOSX folder and .DS_Store files added by macOS
+func isHiddenName(name string) bool {
+	for _, element := range strings.Split(name, "/") {
+		if strings.HasPrefix(element, ".") || element == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
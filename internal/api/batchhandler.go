@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"steganografi/internal/steganography"
+)
+
+// maxBatchExtractedBytes limits the total size of the carriers extracted from a batch archive
+const maxBatchExtractedBytes = 1 << 30 // 1 GB
+
+// HandleBatchEncode hides payloads in every carrier of a ZIP archive and returns
+// a ZIP of the stego carriers with a report.json of per-item status
+func HandleBatchEncode(w http.ResponseWriter, r *http.Request) {
+	// Parse multipart form
+	if !batchArchive.parseForm(w, r) {
+		return
+	}
+
+	// Create a working directory
+	workDir, err := os.MkdirTemp("", "batch_encode_")
+	if err != nil {
+		sendErrorResponse(w, "Failed to create working directory", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(workDir) // Clean up
+
+	// Read the manifest and the default payload
+	manifest, ok := formBatchManifest(w, r)
+	if !ok {
+		return
+	}
+	if message := r.FormValue("message"); message != "" {
+		manifest.Message, manifest.File = message, ""
+	}
+
+	// Save the payload files
+	payloadDir := filepath.Join(workDir, "payloads")
+	payloads := make(map[string]string)
+	headers := r.MultipartForm.File["payloads"]
+	if file := r.MultipartForm.File["file"]; len(file) > 0 {
+		headers = append(headers, file[0])
+		manifest.File, manifest.Message = file[0].Filename, ""
+	}
+	if err := os.Mkdir(payloadDir, 0755); err != nil {
+		sendErrorResponse(w, "Failed to create working directory", http.StatusInternalServerError)
+		return
+	}
+	for i, header := range headers {
+		payloadPath := filepath.Join(payloadDir, strconv.Itoa(i))
+		if err := saveFileHeader(header, payloadPath); err != nil {
+			sendErrorResponse(w, "Failed to save uploaded file", http.StatusInternalServerError)
+			return
+		}
+		payloads[header.Filename] = payloadPath
+	}
+
+	// Extract the carriers
+	carriers, ok := formBatchCarriers(w, r, workDir)
+	if !ok {
+		return
+	}
+
+	// Encode every carrier
+	outputDir := filepath.Join(workDir, "output")
+	job := steganography.NewBatchEncodeJob(manifest, carriers, func(name string) (string, bool) {
+		payloadPath, found := payloads[name]
+		return payloadPath, found
+	}, outputDir)
+	report := job.Encode(steganography.BatchOptions{
+		Seed:     r.FormValue("seed"),
+		Password: r.FormValue("password"),
+		Context:  r.Context(),
+	})
+
+	sendBatchArchive(w, report, outputDir, "stego_batch.zip")
+}
+
+// HandleBatchDecode recovers the hidden files from every carrier of a ZIP archive and
+// returns them in a ZIP with a report.json of per-item status
+func HandleBatchDecode(w http.ResponseWriter, r *http.Request) {
+	// Parse multipart form
+	if !batchArchive.parseForm(w, r) {
+		return
+	}
+
+	// Create a working directory
+	workDir, err := os.MkdirTemp("", "batch_decode_")
+	if err != nil {
+		sendErrorResponse(w, "Failed to create working directory", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(workDir) // Clean up
+
+	// Read the manifest for per-carrier methods
+	manifest, ok := formBatchManifest(w, r)
+	if !ok {
+		return
+	}
+
+	// Extract the carriers
+	carriers, ok := formBatchCarriers(w, r, workDir)
+	if !ok {
+		return
+	}
+
+	// Decode every carrier
+	outputDir := filepath.Join(workDir, "output")
+	report := steganography.NewBatchDecodeJob(manifest, carriers).Decode(steganography.BatchOptions{
+		Seed:     r.FormValue("seed"),
+		Password: r.FormValue("password"),
+		Context:  r.Context(),
+	}, outputDir)
+
+	sendBatchArchive(w, report, outputDir, "recovered_batch.zip")
+}
+
+// Helper functions
+
+// formBatchManifest reads the "manifest" field of a parsed multipart form, given as a
+// file or as text, and applies the "method" and "threshold" fields as the default method
+// It sends an error response and returns false on failure
+func formBatchManifest(w http.ResponseWriter, r *http.Request) (*steganography.BatchManifest, bool) {
+	manifestJSON := []byte(r.FormValue("manifest"))
+	if file, _, err := r.FormFile("manifest"); err == nil {
+		manifestJSON, err = io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			sendErrorResponse(w, "Failed to read manifest", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	manifest := &steganography.BatchManifest{}
+	if len(manifestJSON) > 0 {
+		var err error
+		manifest, err = steganography.ParseBatchManifest(manifestJSON)
+		if err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	if method := r.FormValue("method"); method != "" {
+		manifest.Method = steganography.Method{Name: method}
+		if thresholdStr := r.FormValue("threshold"); thresholdStr != "" {
+			threshold, err := strconv.ParseFloat(thresholdStr, 64)
+			if err != nil {
+				sendErrorResponse(w, "Invalid threshold: "+thresholdStr, http.StatusBadRequest)
+				return nil, false
+			}
+			manifest.Method.ComplexityThreshold = threshold
+		}
+	}
+
+	return manifest, true
+}
+
+// formBatchCarriers saves the "archive" ZIP of a parsed multipart form and extracts its carriers
+// It sends an error response and returns false on failure
+func formBatchCarriers(w http.ResponseWriter, r *http.Request, workDir string) ([]steganography.BatchCarrier, bool) {
+	headers := r.MultipartForm.File[batchArchive.field]
+	if len(headers) == 0 {
+		sendErrorResponse(w, "No carrier archive provided", http.StatusBadRequest)
+		return nil, false
+	}
+
+	archivePath := filepath.Join(workDir, "carriers.zip")
+	if err := saveFileHeader(headers[0], archivePath); err != nil {
+		sendErrorResponse(w, "Failed to save uploaded file", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	carrierDir := filepath.Join(workDir, "carriers")
+	if err := os.Mkdir(carrierDir, 0755); err != nil {
+		sendErrorResponse(w, "Failed to create working directory", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	carriers, err := ExtractBatchArchive(archivePath, carrierDir, maxBatchExtractedBytes)
+	if err != nil {
+		sendErrorResponse(w, "Failed to extract archive: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return carriers, true
+}
+
+// saveFileHeader saves an uploaded file of a multipart form to the specified path
+func saveFileHeader(header *multipart.FileHeader, path string) error {
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return SaveUploadedFile(file, path)
+}
+
+// sendBatchArchive sends the outputs of a batch and its report in a ZIP archive
+func sendBatchArchive(w http.ResponseWriter, report *steganography.BatchReport, outputDir, fileName string) {
+	var archive bytes.Buffer
+	if err := WriteBatchArchive(&archive, report, outputDir); err != nil {
+		sendErrorResponse(w, "Failed to create archive", http.StatusInternalServerError)
+		return
+	}
+
+	// Set headers for file download
+	w.Header().Set("X-Batch-Succeeded", strconv.Itoa(report.Succeeded))
+	w.Header().Set("X-Batch-Failed", strconv.Itoa(report.Failed))
+	w.Header().Set("Access-Control-Expose-Headers", "X-Batch-Succeeded, X-Batch-Failed")
+	w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+	w.Header().Set("Content-Type", "application/zip")
+
+	// Send the archive
+	w.Write(archive.Bytes())
+}
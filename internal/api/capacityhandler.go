@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"steganografi/internal/steganography"
+)
+
+// CapacityOverhead lists the bytes taken from the carrier capacity before the user's data
+type CapacityOverhead struct {
+	Encryption   int `json:"encryption"`   // AES-GCM nonce and tag, when the payload is encrypted
+	FileMetadata int `json:"fileMetadata"` // Length prefix and JSON metadata of a file payload
+	ECC          int `json:"ecc"`          // Error correction; none of the encoders use it yet
+}
+
+// CapacityResponse is the result of a capacity estimate
+// Capacity already excludes the encoder's own length header and encryption
+type CapacityResponse struct {
+	Carrier         string           `json:"carrier"` // "image", "audio" or "video"
+	Capacity        int              `json:"capacity"`
+	MaxMessageBytes int              `json:"maxMessageBytes"` // Largest text message
+	MaxFileBytes    int              `json:"maxFileBytes"`    // Largest file named fileName
+	Unlimited       bool             `json:"unlimited"`       // The mode is not bounded by the carrier
+	Overhead        CapacityOverhead `json:"overhead"`
+}
+
+// HandleCapacity reports the largest payload a carrier holds with the given settings
+func HandleCapacity(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the carrier
+	upload, ok := anyCarrier.parseUpload(w, r, "capacity_")
+	if !ok {
+		return
+	}
+	defer os.Remove(upload.inputPath) // Clean up
+
+	// Get form values
+	seed := r.FormValue("seed")
+	fileName := r.FormValue("fileName")
+
+	// Create the encoder for the carrier type
+	carrier, embedder, err := newFormEncoder(r, seed, upload.ext)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	encoder, ok := embedder.(steganography.CapacityEstimator)
+	if !ok {
+		sendErrorResponse(w, "Capacity estimation is not supported for this method", http.StatusBadRequest)
+		return
+	}
+
+	// Estimate the capacity
+	capacity, err := encoder.Capacity(upload.inputPath)
+	if err != nil {
+		sendErrorResponse(w, "Failed to estimate capacity: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := CapacityResponse{
+		Carrier:         carrier,
+		Capacity:        capacity,
+		MaxMessageBytes: capacity,
+		MaxFileBytes:    capacity,
+		Unlimited:       capacity == steganography.UnlimitedCapacity,
+	}
+
+	// Only the audio encoder encrypts; its container modes always do
+	if audio, ok := encoder.(*steganography.AudioEncoder); ok {
+		if audio.Password != "" || (audio.Mode != "" && audio.Mode != steganography.AudioModeLSB) {
+			response.Overhead.Encryption = steganography.EncryptionOverhead
+		}
+	}
+
+	// File payloads carry their metadata
+	response.Overhead.FileMetadata = fileMetadataOverhead(fileName, capacity)
+	if !response.Unlimited {
+		response.MaxFileBytes = max(capacity-response.Overhead.FileMetadata, 0)
+	}
+
+	// Send the response
+	sendSuccessResponse(w, "Capacity estimated successfully", response)
+}
+
+// fileMetadataOverhead returns the bytes PrepareFileData adds to a file of at most
+// capacity bytes: the 4-byte length prefix and the JSON metadata
+func fileMetadataOverhead(fileName string, capacity int) int {
+	metadata := steganography.FileMetadata{
+		FileName: fileName,
+		FileExt:  filepath.Ext(fileName),
+		FileSize: max(capacity, 0), // Upper bound for the size field
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return 4
+	}
+	return 4 + len(metadataJSON)
+}
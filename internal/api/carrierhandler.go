@@ -0,0 +1,336 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"image"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"steganografi/internal/steganography"
+)
+
+// carrierRequest is a carrier saved from a request with the encoder for it
+type carrierRequest struct {
+	inputPath string
+	name      string // Uploaded file name
+	ext       string // Lowercase extension of the uploaded carrier
+	timestamp string // Shared by every temporary file of the request
+	encoder   steganography.Embedder
+}
+
+// handleEncode returns the handler that hides a text message, or a file with
+// its metadata, in the carrier and sends back the stego carrier
+func (route carrierRoute) handleEncode(isFile bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse the form and save the carrier
+		carrier, ok := route.parseRequest(w, r, "input_")
+		if !ok {
+			return
+		}
+		defer os.Remove(carrier.inputPath) // Clean up
+
+		// Get the payload
+		what := "message"
+		payload := []byte(r.FormValue("message"))
+		if isFile {
+			what = "file"
+			if payload, ok = formDataFile(w, r, carrier.timestamp); !ok {
+				return
+			}
+		}
+
+		// Run the encoder
+		fileName, contentType := route.kind.output(carrier.ext)
+		outputPath := filepath.Join(os.TempDir(), "output_"+carrier.timestamp+filepath.Ext(fileName))
+		defer os.Remove(outputPath) // Clean up
+		err := carrier.encoder.EncodeDataContext(r.Context(), carrier.inputPath, outputPath, payload, nil)
+		if err != nil {
+			sendErrorResponse(w, "Failed to encode "+what+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Report how the carrier changed
+		if route.kind.report != nil {
+			route.kind.report(w, r, carrier.inputPath, outputPath)
+		}
+
+		// Send the file
+		if err := sendFile(w, outputPath, fileName, contentType); err != nil {
+			sendErrorResponse(w, "Failed to send output file", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// handleDecode returns the handler that recovers a text message, or a file with
+// its metadata, from the carrier
+func (route carrierRoute) handleDecode(isFile bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse the form and save the carrier
+		carrier, ok := route.parseRequest(w, r, "decode_")
+		if !ok {
+			return
+		}
+		defer os.Remove(carrier.inputPath) // Clean up
+
+		// Run the decoder
+		data, recovery, err := carrier.decode(r)
+		if err != nil {
+			what := "message"
+			if isFile {
+				what = "data"
+			}
+			sendErrorResponse(w, "Failed to decode "+what+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if !isFile {
+			// Send the response
+			response := map[string]interface{}{
+				"message": string(data),
+			}
+			recovery.addTo(response)
+			sendSuccessResponse(w, "Message decoded successfully", response)
+			return
+		}
+
+		// Split the file metadata from its contents
+		metadata, fileData, err := steganography.ExtractFileData(data)
+		if err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Send the response
+		response := map[string]interface{}{
+			"fileName": metadata.FileName,
+			"fileExt":  metadata.FileExt,
+			"fileSize": metadata.FileSize,
+			"fileData": base64.StdEncoding.EncodeToString(fileData),
+		}
+		recovery.addTo(response)
+		sendSuccessResponse(w, "File decoded successfully", response)
+	}
+}
+
+// frameRecovery describes a partially decoded payload in a decode response
+type frameRecovery steganography.FrameRecovery
+
+// decode runs the carrier's decoder. With the partial form field set to "true",
+// a per-frame video payload is recovered from the surviving frames: missing
+// segments are zero-filled and listed in the returned recovery.
+func (carrier *carrierRequest) decode(r *http.Request) ([]byte, *frameRecovery, error) {
+	if r.FormValue("partial") != "true" {
+		data, err := carrier.encoder.DecodeDataContext(r.Context(), carrier.inputPath, nil)
+		return data, nil, err
+	}
+
+	frames, ok := carrier.encoder.(*steganography.VideoFrameEncoder)
+	if !ok {
+		return nil, nil, errors.New("partial decoding requires the video frames mode")
+	}
+	recovery, err := frames.DecodePartialContext(r.Context(), carrier.inputPath, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return recovery.Data, (*frameRecovery)(recovery), nil
+}
+
+// addTo adds the segment counts of a partial decode to a response
+func (recovery *frameRecovery) addTo(response map[string]interface{}) {
+	if recovery == nil {
+		return
+	}
+	response["segmentCount"] = recovery.SegmentCount
+	response["missingSegments"] = recovery.MissingSegments
+}
+
+// parseRequest parses the form, saves the carrier and creates the route's
+// encoder. On failure it sends the error response and returns false; otherwise
+// the caller removes the saved carrier.
+func (route carrierRoute) parseRequest(w http.ResponseWriter, r *http.Request, prefix string) (*carrierRequest, bool) {
+	carrier, ok := route.kind.parseUpload(w, r, prefix)
+	if !ok {
+		return nil, false
+	}
+
+	// Create the encoder
+	encoder, err := route.newEncoder(r, r.FormValue("seed"), carrier.ext)
+	if err != nil {
+		os.Remove(carrier.inputPath)
+		sendErrorResponse(w, "Failed to create encoder: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	carrier.encoder = encoder
+
+	return carrier, true
+}
+
+// parseUpload checks the method, parses the form within the kind's memory limit
+// and saves the carrier to a temporary file named prefix, timestamp and the
+// lowercase extension. The returned request has no encoder. On failure it sends
+// the error response and returns false; otherwise the caller removes the file.
+func (kind *carrierKind) parseUpload(w http.ResponseWriter, r *http.Request, prefix string) (*carrierRequest, bool) {
+	if !kind.parseForm(w, r) {
+		return nil, false
+	}
+	return kind.saveFile(w, r, kind.field, prefix)
+}
+
+// parseImage checks the method, parses the form within the kind's memory limit
+// and decodes the uploaded image. On failure it sends the error response and
+// returns false.
+func (kind *carrierKind) parseImage(w http.ResponseWriter, r *http.Request) (image.Image, bool) {
+	if !kind.parseForm(w, r) {
+		return nil, false
+	}
+	return kind.formImage(w, r, kind.field)
+}
+
+// parseUploads checks the method, parses the form within the kind's memory limit
+// and saves every carrier of the kind's field with SaveUploadedFiles. On failure
+// it removes the saved files, sends the error response and returns false;
+// otherwise the caller removes the files.
+func (kind *carrierKind) parseUploads(w http.ResponseWriter, r *http.Request, prefix string) ([]string, []string, bool) {
+	if !kind.parseForm(w, r) {
+		return nil, nil, false
+	}
+
+	// Validate the carriers
+	headers := r.MultipartForm.File[kind.field]
+	if len(headers) == 0 {
+		sendErrorResponse(w, "No carrier files provided", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	for _, header := range headers {
+		if kind.accepts != nil && !kind.accepts(strings.ToLower(filepath.Ext(header.Filename))) {
+			sendErrorResponse(w, kind.rejectMessage, http.StatusBadRequest)
+			return nil, nil, false
+		}
+	}
+
+	// Save the uploaded files
+	names, paths, err := SaveUploadedFiles(headers, prefix)
+	if err != nil {
+		removeFiles(paths)
+		sendErrorResponse(w, "Failed to save uploaded file", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	return names, paths, true
+}
+
+// parseForm checks the method and parses the form within the kind's memory limit
+// On failure it sends the error response and returns false.
+func (kind *carrierKind) parseForm(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	// Parse multipart form
+	err := r.ParseMultipartForm(kind.maxMemory)
+	if err != nil {
+		sendErrorResponse(w, "Failed to parse form", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// saveFile saves a carrier file field of a parsed form like parseUpload
+// On failure it sends the error response and returns false.
+func (kind *carrierKind) saveFile(w http.ResponseWriter, r *http.Request, field, prefix string) (*carrierRequest, bool) {
+	file, handler, ext, ok := kind.formFile(w, r, field)
+	if !ok {
+		return nil, false
+	}
+	defer file.Close()
+
+	// Save the uploaded file
+	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+	inputPath := filepath.Join(os.TempDir(), prefix+timestamp+ext)
+	if err := SaveUploadedFile(file, inputPath); err != nil {
+		os.Remove(inputPath)
+		sendErrorResponse(w, "Failed to save uploaded file", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return &carrierRequest{
+		inputPath: inputPath,
+		name:      handler.Filename,
+		ext:       ext,
+		timestamp: timestamp,
+	}, true
+}
+
+// formImage decodes an image file field of a parsed form
+// On failure it sends the error response and returns false.
+func (kind *carrierKind) formImage(w http.ResponseWriter, r *http.Request, field string) (image.Image, bool) {
+	file, _, _, ok := kind.formFile(w, r, field)
+	if !ok {
+		return nil, false
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		sendErrorResponse(w, "Failed to decode image: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return img, true
+}
+
+// formFile opens a carrier file field of a parsed form and checks its extension
+// It returns the lowercase extension. On failure it sends the error response and
+// returns false; otherwise the caller closes the file.
+func (kind *carrierKind) formFile(w http.ResponseWriter, r *http.Request, field string) (multipart.File, *multipart.FileHeader, string, bool) {
+	file, handler, err := r.FormFile(field)
+	if err != nil {
+		sendErrorResponse(w, "Failed to get "+field+" file", http.StatusBadRequest)
+		return nil, nil, "", false
+	}
+
+	// Validate file extension
+	ext := strings.ToLower(filepath.Ext(handler.Filename))
+	if kind.accepts != nil && !kind.accepts(ext) {
+		file.Close()
+		sendErrorResponse(w, kind.rejectMessage, http.StatusBadRequest)
+		return nil, nil, "", false
+	}
+
+	return file, handler, ext, true
+}
+
+// formDataFile reads the "file" form field and combines it with its metadata
+// On failure it sends the error response and returns false.
+func formDataFile(w http.ResponseWriter, r *http.Request, timestamp string) ([]byte, bool) {
+	dataFile, dataHandler, err := r.FormFile("file")
+	if err != nil {
+		sendErrorResponse(w, "Failed to get data file", http.StatusBadRequest)
+		return nil, false
+	}
+	defer dataFile.Close()
+
+	// Save the uploaded data file
+	inputDataPath := filepath.Join(os.TempDir(), "input_data_"+timestamp+filepath.Ext(dataHandler.Filename))
+	defer os.Remove(inputDataPath) // Clean up
+	if err := SaveUploadedFile(dataFile, inputDataPath); err != nil {
+		sendErrorResponse(w, "Failed to save uploaded data file", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	// Combine file metadata and contents
+	combinedData, err := steganography.PrepareFileData(inputDataPath, dataHandler.Filename)
+	if err != nil {
+		sendErrorResponse(w, "Failed to read data file", http.StatusInternalServerError)
+		return nil, false
+	}
+	return combinedData, true
+}
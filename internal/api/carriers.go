@@ -0,0 +1,230 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"steganografi/internal/steganography"
+)
+
+// carrierKind describes a kind of carrier file served by the carrier pipeline
+type carrierKind struct {
+	field     string // Multipart field holding the carrier, e.g. "image"
+	maxMemory int64  // Limit passed to ParseMultipartForm
+
+	// accepts reports whether a lowercase extension is a supported carrier; nil accepts any
+	accepts       func(ext string) bool
+	rejectMessage string
+
+	// output returns the download name and content type of a stego carrier
+	// The extension of the name is the extension of the output file.
+	output func(ext string) (fileName, contentType string)
+
+	// report adds cover/stego comparisons to the response headers; nil adds none
+	report func(w http.ResponseWriter, r *http.Request, coverPath, stegoPath string)
+}
+
+// carrierRoute is a family of encode and decode routes served by the carrier pipeline
+// Each route gets /encode/text, /encode/file, /decode/text and /decode/file.
+type carrierRoute struct {
+	prefix     string // URL prefix, e.g. "/api/lsb"
+	kind       *carrierKind
+	newEncoder func(r *http.Request, seed, ext string) (steganography.Embedder, error)
+}
+
+// Carrier kinds
+var (
+	imageCarrier = &carrierKind{
+		field:         "image",
+		maxMemory:     50 << 20, // 50 MB max for the image and data file
+		accepts:       isSupportedImageExt,
+		rejectMessage: "Only PNG and JPEG images are supported",
+		output: func(ext string) (string, string) {
+			return "stego_image.png", "image/png" // Images are always written as PNG
+		},
+		report: setImageMetricsHeaders,
+	}
+
+	audioCarrier = &carrierKind{
+		field:         "audio",
+		maxMemory:     50 << 20, // 50 MB max for the audio and data file
+		accepts:       isSupportedAudioExt,
+		rejectMessage: "Only WAV and AIFF files are supported",
+		output: func(ext string) (string, string) {
+			return "stego_audio" + ext, audioContentType(ext)
+		},
+		report: setAudioMetricsHeaders,
+	}
+
+	videoCarrier = &carrierKind{
+		field:         "video",
+		maxMemory:     50 << 20, // 50 MB max for the video and data file
+		accepts:       isSupportedVideoExt,
+		rejectMessage: "Only AVI and Y4M files are supported",
+		output: func(ext string) (string, string) {
+			return "stego_video" + ext, videoContentType(ext)
+		},
+	}
+
+	// anyCarrier is a carrier of any supported type
+	// The job and capacity handlers pick the encoder from its extension.
+	anyCarrier = &carrierKind{
+		field:         "carrier",
+		maxMemory:     200 << 20, // 200 MB max for large carriers
+		accepts:       isSupportedCarrierExt,
+		rejectMessage: "Unsupported carrier file type",
+	}
+
+	// batchArchive is the ZIP of carriers of a batch request, with its payloads
+	batchArchive = &carrierKind{
+		field:     "archive",
+		maxMemory: 500 << 20, // 500 MB max for the archive and payloads
+	}
+
+	// carrierSet is the carriers of a split, share or plan request
+	// Unsupported carriers are reported one by one, so any file is accepted.
+	carrierSet = &carrierKind{
+		field:     "carriers",
+		maxMemory: 200 << 20, // 200 MB max for all carriers
+	}
+)
+
+// carrierRoutes lists the routes served by the carrier pipeline
+// Adding a method only needs an entry here.
+var carrierRoutes = []carrierRoute{
+	{
+		prefix: "/api/lsb",
+		kind:   imageCarrier,
+		newEncoder: func(r *http.Request, seed, ext string) (steganography.Embedder, error) {
+			return steganography.NewLSBEncoder(seed)
+		},
+	},
+	{
+		prefix: "/api/bpcs",
+		kind:   imageCarrier,
+		newEncoder: func(r *http.Request, seed, ext string) (steganography.Embedder, error) {
+			return steganography.NewBPCSEncoder(seed, formComplexityThreshold(r))
+		},
+	},
+	{
+		prefix:     "/api/audio",
+		kind:       audioCarrier,
+		newEncoder: newAudioEmbedder,
+	},
+	{
+		prefix:     "/api/video",
+		kind:       videoCarrier,
+		newEncoder: newVideoEmbedder,
+	},
+}
+
+// RegisterCarrierRoutes registers the encode and decode routes of every carrier method
+func RegisterCarrierRoutes(mux *http.ServeMux) {
+	for _, route := range carrierRoutes {
+		mux.HandleFunc(route.prefix+"/encode/text", route.handleEncode(false))
+		mux.HandleFunc(route.prefix+"/encode/file", route.handleEncode(true))
+		mux.HandleFunc(route.prefix+"/decode/text", route.handleDecode(false))
+		mux.HandleFunc(route.prefix+"/decode/file", route.handleDecode(true))
+	}
+}
+
+// Helper functions
+
+// newAudioEmbedder creates the audio encoder for the mode and password form fields
+func newAudioEmbedder(r *http.Request, seed, ext string) (steganography.Embedder, error) {
+	mode, err := steganography.ParseAudioMode(r.FormValue("mode"))
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := steganography.NewAudioEncoder(seed)
+	if err != nil {
+		return nil, err
+	}
+	encoder.Mode = mode
+	encoder.Password = r.FormValue("password")
+	return encoder, nil
+}
+
+// newVideoEmbedder creates the video encoder for the carrier extension and form fields
+// Mode "frames" applies the LSB or BPCS image method to each AVI or Y4M frame.
+// Otherwise Y4M carriers use the plane and bitDepth fields, and AVI carriers with
+// mode "bytes" (default) spread bits over all frame bytes.
+func newVideoEmbedder(r *http.Request, seed, ext string) (steganography.Embedder, error) {
+	if r.FormValue("mode") == "frames" {
+		return steganography.NewVideoFrameEncoder(seed, r.FormValue("method"), formComplexityThreshold(r))
+	}
+
+	if ext == ".y4m" {
+		bitDepth := 1 // Default value
+		if bitDepthStr := r.FormValue("bitDepth"); bitDepthStr != "" {
+			var err error
+			bitDepth, err = strconv.Atoi(bitDepthStr)
+			if err != nil {
+				return nil, errors.New("invalid bit depth: " + bitDepthStr)
+			}
+		}
+		return steganography.NewYUVEncoder(seed, r.FormValue("plane"), bitDepth)
+	}
+
+	switch r.FormValue("mode") {
+	case "", "bytes":
+		return steganography.NewVideoEncoder(seed)
+	}
+	return nil, errors.New("unknown video mode: " + r.FormValue("mode"))
+}
+
+// formComplexityThreshold returns the BPCS complexity threshold form field,
+// falling back to the default when it is missing or out of range
+func formComplexityThreshold(r *http.Request) float64 {
+	complexityThreshold := 0.45 // Default value
+	if complexityThresholdStr := r.FormValue("complexityThreshold"); complexityThresholdStr != "" {
+		var err error
+		complexityThreshold, err = strconv.ParseFloat(complexityThresholdStr, 64)
+		if err != nil || complexityThreshold < 0.3 || complexityThreshold >= 0.5 {
+			complexityThreshold = 0.45 // Default if invalid
+		}
+	}
+	return complexityThreshold
+}
+
+// isSupportedImageExt reports whether the extension is an accepted image carrier
+func isSupportedImageExt(ext string) bool {
+	return ext == ".png" || ext == ".jpg" || ext == ".jpeg"
+}
+
+// isSupportedCarrierExt reports whether the extension is an accepted carrier of any type
+func isSupportedCarrierExt(ext string) bool {
+	return isSupportedImageExt(ext) || isSupportedAudioExt(ext) || isSupportedVideoExt(ext)
+}
+
+// isSupportedAudioExt reports whether the extension is an accepted audio carrier
+func isSupportedAudioExt(ext string) bool {
+	switch ext {
+	case ".wav", ".rf64", ".bw64", ".aif", ".aiff", ".aifc":
+		return true
+	}
+	return false
+}
+
+// audioContentType returns the MIME type for an audio carrier extension
+func audioContentType(ext string) string {
+	switch ext {
+	case ".aif", ".aiff", ".aifc":
+		return "audio/aiff"
+	}
+	return "audio/wav"
+}
+
+// isSupportedVideoExt reports whether the extension is an accepted video carrier
+func isSupportedVideoExt(ext string) bool {
+	return ext == ".avi" || ext == ".y4m"
+}
+
+// videoContentType returns the MIME type for a video carrier extension
+func videoContentType(ext string) string {
+	if ext == ".y4m" {
+		return "video/x-yuv4mpeg"
+	}
+	return "video/x-msvideo"
+}
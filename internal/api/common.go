@@ -2,9 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"strconv"
+
+	"steganografi/internal/steganography"
 )
 
 // Response represents the API response structure
@@ -34,41 +37,67 @@ func sendSuccessResponse(w http.ResponseWriter, message string, data any) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// Utility function to optimize image size
-func optimizeImageSize(inputPath, outputPath string) error {
-	// Read the original file to get its size
-	originalFile, err := os.Open(inputPath)
-	if err != nil {
-		return err
+// setImageMetricsHeaders adds cover/stego quality metrics to the response headers
+// when the request sets the "metrics" form field to "true"
+func setImageMetricsHeaders(w http.ResponseWriter, r *http.Request, coverPath, stegoPath string) {
+	if r.FormValue("metrics") != "true" {
+		return
 	}
-	defer originalFile.Close()
 
-	originalInfo, err := originalFile.Stat()
+	metrics, err := steganography.CompareImageFiles(coverPath, stegoPath)
 	if err != nil {
-		return err
+		// Just log the error, don't fail the operation
+		fmt.Printf("Warning: Failed to compute image metrics: %v\n", err)
+		return
 	}
-	originalSize := originalInfo.Size()
 
-	// Read the encoded file to get its size
-	encodedFile, err := os.Open(outputPath)
-	if err != nil {
-		return err
+	w.Header().Set("X-Stego-MSE", strconv.FormatFloat(metrics.MSE, 'f', 6, 64))
+	w.Header().Set("X-Stego-PSNR", strconv.FormatFloat(metrics.PSNR, 'f', 2, 64))
+	w.Header().Set("X-Stego-SSIM", strconv.FormatFloat(metrics.SSIM, 'f', 6, 64))
+	w.Header().Set("X-Stego-Changed-Pixels", strconv.Itoa(metrics.ChangedPixels))
+	w.Header().Set("Access-Control-Expose-Headers", "X-Stego-MSE, X-Stego-PSNR, X-Stego-SSIM, X-Stego-Changed-Pixels")
+}
+
+// setAudioMetricsHeaders adds cover/stego distortion metrics to the response headers
+// when the request sets the "metrics" form field to "true"
+func setAudioMetricsHeaders(w http.ResponseWriter, r *http.Request, coverPath, stegoPath string) {
+	if r.FormValue("metrics") != "true" {
+		return
 	}
-	defer encodedFile.Close()
 
-	encodedInfo, err := encodedFile.Stat()
+	metrics, err := steganography.CompareAudioFiles(coverPath, stegoPath)
 	if err != nil {
-		return err
+		// Just log the error, don't fail the operation
+		fmt.Printf("Warning: Failed to compute audio metrics: %v\n", err)
+		return
 	}
-	encodedSize := encodedInfo.Size()
 
-	// If the encoded file is significantly larger, try to optimize it
-	if float64(encodedSize) > float64(originalSize)*1.2 { // If more than 20% larger
-		// For now, we'll just log this - in a real implementation,
-		// you might use an image optimization library here
-		fmt.Printf("Warning: Encoded file is %.2f%% larger than original\n",
-			float64(encodedSize-originalSize)/float64(originalSize)*100)
+	w.Header().Set("X-Stego-SNR", strconv.FormatFloat(metrics.SNR, 'f', 2, 64))
+	w.Header().Set("X-Stego-Segmental-SNR", strconv.FormatFloat(metrics.SegmentalSNR, 'f', 2, 64))
+	w.Header().Set("X-Stego-Peak-Deviation", strconv.Itoa(metrics.PeakDeviation))
+	w.Header().Set("Access-Control-Expose-Headers", "X-Stego-SNR, X-Stego-Segmental-SNR, X-Stego-Peak-Deviation")
+}
+
+// newFormEncoder creates the encoder matching the carrier extension and form settings
+// It returns the carrier type: "image", "audio" or "video"
+func newFormEncoder(r *http.Request, seed, ext string) (string, steganography.Embedder, error) {
+	switch {
+	case isSupportedAudioExt(ext):
+		encoder, err := newAudioEmbedder(r, seed, ext)
+		return "audio", encoder, err
+
+	case isSupportedVideoExt(ext):
+		encoder, err := newVideoEmbedder(r, seed, ext)
+		return "video", encoder, err
 	}
 
-	return nil
+	switch r.FormValue("method") {
+	case "", "lsb":
+		encoder, err := steganography.NewLSBEncoder(seed)
+		return "image", encoder, err
+	case "bpcs":
+		encoder, err := steganography.NewBPCSEncoder(seed, formComplexityThreshold(r))
+		return "image", encoder, err
+	}
+	return "", nil, errors.New("unknown image method: " + r.FormValue("method"))
 }
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"steganografi/internal/steganography"
+)
+
+// HandleDeniableEncode hides a message under a password in an image, together with
+// an optional decoy message under its own password. Without a decoy the other half
+// is filled with noise, so the output looks the same either way.
+func HandleDeniableEncode(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the image
+	carrier, ok := imageCarrier.parseUpload(w, r, "deniable_input_")
+	if !ok {
+		return
+	}
+	inputPath := carrier.inputPath
+	defer os.Remove(inputPath) // Clean up
+
+	// Get form values
+	seed := r.FormValue("seed")
+	message := r.FormValue("message")
+	password := r.FormValue("password")
+	decoyMessage := r.FormValue("decoyMessage")
+	decoyPassword := r.FormValue("decoyPassword")
+
+	// Create output file path
+	outputPath := filepath.Join(os.TempDir(), "deniable_output_"+carrier.timestamp+".png")
+
+	// Create deniable encoder
+	encoder, err := steganography.NewDeniableEncoder(seed)
+	if err != nil {
+		sendErrorResponse(w, "Failed to create encoder: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Encode the message and the decoy, if any
+	if decoyMessage == "" && decoyPassword == "" {
+		err = encoder.EncodeContext(r.Context(), inputPath, outputPath, []byte(message), password, nil)
+	} else {
+		err = encoder.EncodeDualContext(r.Context(), inputPath, outputPath, []byte(decoyMessage), decoyPassword, []byte(message), password, nil)
+	}
+	if err != nil {
+		sendErrorResponse(w, "Failed to encode messages: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	defer os.Remove(outputPath) // Clean up
+
+	// Send the file
+	if err := SendFileForDownload(w, outputPath, "stego_image.png"); err != nil {
+		sendErrorResponse(w, "Failed to send output file", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeniableDecode extracts the message hidden under a password
+func HandleDeniableDecode(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the image
+	carrier, ok := imageCarrier.parseUpload(w, r, "deniable_decode_")
+	if !ok {
+		return
+	}
+	inputPath := carrier.inputPath
+	defer os.Remove(inputPath) // Clean up
+
+	// Get form values
+	seed := r.FormValue("seed")
+	password := r.FormValue("password")
+
+	// Create deniable encoder
+	encoder, err := steganography.NewDeniableEncoder(seed)
+	if err != nil {
+		sendErrorResponse(w, "Failed to create encoder: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Decode the message
+	data, err := encoder.DecodeContext(r.Context(), inputPath, password, nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to decode message: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Send the response
+	sendSuccessResponse(w, "Message decoded successfully", map[string]string{
+		"message": string(data),
+	})
+}
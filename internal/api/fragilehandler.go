@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+
+	"steganografi/internal/steganography"
+)
+
+// HandleFragileEmbed authenticates every 8x8 block of an image with a fragile watermark
+func HandleFragileEmbed(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and decode the image
+	img, ok := imageCarrier.parseImage(w, r)
+	if !ok {
+		return
+	}
+
+	// Get form values
+	seed := r.FormValue("seed")
+
+	// Create fragile encoder
+	encoder, err := steganography.NewFragileEncoder(seed)
+	if err != nil {
+		sendErrorResponse(w, "Failed to create encoder: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Embed the watermark
+	marked, err := encoder.EmbedImageContext(r.Context(), img, nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to embed watermark: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sendPNG(w, marked, "authenticated_image.png")
+}
+
+// HandleFragileVerify checks the fragile watermark of an image and reports the tampered blocks
+// With "output" set to "map" it returns the image with tampered blocks tinted red instead
+func HandleFragileVerify(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and decode the image
+	img, ok := imageCarrier.parseImage(w, r)
+	if !ok {
+		return
+	}
+
+	// Get form values
+	seed := r.FormValue("seed")
+
+	// Create fragile encoder
+	encoder, err := steganography.NewFragileEncoder(seed)
+	if err != nil {
+		sendErrorResponse(w, "Failed to create encoder: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Verify every block
+	report, err := encoder.VerifyImageContext(r.Context(), img, nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to verify image: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("output") == "map" {
+		sendPNG(w, steganography.TamperMapImage(img, report), "tamper_map.png")
+		return
+	}
+
+	// Send the response
+	message := "Image is authentic"
+	switch {
+	case report.TamperedBlocks == report.TotalBlocks:
+		message = "No block verifies: the image was not watermarked with this seed, or was resized or recompressed"
+	case report.TamperedBlocks > 0:
+		message = "Image has been tampered with"
+	}
+	sendSuccessResponse(w, message, report)
+}
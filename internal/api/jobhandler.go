@@ -0,0 +1,302 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"steganografi/internal/jobs"
+	"steganografi/internal/steganography"
+)
+
+// jobManager runs the background jobs; nil until SetJobManager is called
+var jobManager *jobs.Manager
+
+// SetJobManager sets the manager that runs the background job endpoints
+func SetJobManager(manager *jobs.Manager) {
+	jobManager = manager
+}
+
+// HandleJobEncode queues hiding a message or file in a carrier and returns the job
+// It takes the same form fields as the encode endpoint for the carrier type.
+func HandleJobEncode(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the carrier for the job
+	carrier, ok := parseJobUpload(w, r)
+	if !ok {
+		return
+	}
+	inputPath, name, ext := carrier.inputPath, carrier.name, carrier.ext
+
+	// Get form values
+	seed := r.FormValue("seed")
+	message := r.FormValue("message")
+
+	// Create the encoder for the carrier type
+	carrierType, encoder, err := newFormEncoder(r, seed, ext)
+	if err != nil {
+		os.Remove(inputPath)
+		sendErrorResponse(w, "Failed to create encoder: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Prepare the payload: a file with its metadata, or the raw message
+	payload := []byte(message)
+	if file, handler, err := r.FormFile("file"); err == nil {
+		dataPath := inputPath + ".payload"
+		err = SaveUploadedFile(file, dataPath)
+		file.Close()
+		if err == nil {
+			payload, err = steganography.PrepareFileData(dataPath, handler.Filename)
+		}
+		os.Remove(dataPath)
+		if err != nil {
+			os.Remove(inputPath)
+			sendErrorResponse(w, "Failed to read data file", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Image carriers are written as PNG; other carriers keep their format
+	outputExt, contentType := ".png", "image/png"
+	switch carrierType {
+	case "audio":
+		outputExt, contentType = ext, audioContentType(ext)
+	case "video":
+		outputExt, contentType = ext, videoContentType(ext)
+	}
+
+	submitJob(w, "encode", inputPath, func(ctx context.Context, progress func(float64)) (*jobs.Result, error) {
+		outputPath := strings.TrimSuffix(inputPath, ext) + "_output" + outputExt
+		if err := encoder.EncodeDataContext(ctx, inputPath, outputPath, payload, progress); err != nil {
+			os.Remove(outputPath)
+			return nil, err
+		}
+
+		return &jobs.Result{
+			FileName:    "stego_" + strings.TrimSuffix(name, filepath.Ext(name)) + outputExt,
+			ContentType: contentType,
+			Path:        outputPath,
+		}, nil
+	})
+}
+
+// HandleJobDecode queues recovering a message or file from a carrier and returns the job
+// With "type" set to "file" the result is the hidden file; otherwise it is the message.
+func HandleJobDecode(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the carrier for the job
+	carrier, ok := parseJobUpload(w, r)
+	if !ok {
+		return
+	}
+	inputPath := carrier.inputPath
+
+	// Get form values
+	seed := r.FormValue("seed")
+	isFile := r.FormValue("type") == "file"
+
+	// Create the encoder for the carrier type
+	_, encoder, err := newFormEncoder(r, seed, carrier.ext)
+	if err != nil {
+		os.Remove(inputPath)
+		sendErrorResponse(w, "Failed to create encoder: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	submitJob(w, "decode", inputPath, func(ctx context.Context, progress func(float64)) (*jobs.Result, error) {
+		data, err := encoder.DecodeDataContext(ctx, inputPath, progress)
+		if err != nil {
+			return nil, err
+		}
+		if !isFile {
+			return &jobs.Result{Data: map[string]string{"message": string(data)}}, nil
+		}
+
+		// Unpack the file and keep it as the result
+		metadata, fileData, err := steganography.ExtractFileData(data)
+		if err != nil {
+			return nil, err
+		}
+		outputPath := inputPath + ".recovered"
+		if err := os.WriteFile(outputPath, fileData, 0644); err != nil {
+			return nil, err
+		}
+
+		return &jobs.Result{
+			FileName:    filepath.Base(metadata.FileName),
+			ContentType: "application/octet-stream",
+			Path:        outputPath,
+			Data:        metadata,
+		}, nil
+	})
+}
+
+// HandleJobs lists all jobs
+func HandleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !jobsEnabled(w) {
+		return
+	}
+
+	list, err := jobManager.List()
+	if err != nil {
+		sendErrorResponse(w, "Failed to list jobs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, "Jobs listed successfully", list)
+}
+
+// HandleJob reports the status and progress of a job on GET, and cancels and
+// removes it with its result on DELETE
+func HandleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !jobsEnabled(w) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if r.Method == http.MethodDelete {
+		if err := jobManager.Delete(id); err != nil {
+			sendJobError(w, err)
+			return
+		}
+		sendSuccessResponse(w, "Job deleted successfully", nil)
+		return
+	}
+
+	job, err := jobManager.Get(id)
+	if err != nil {
+		sendJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "Job status retrieved successfully", job)
+}
+
+// HandleJobCancel stops a queued or running job
+func HandleJobCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !jobsEnabled(w) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := jobManager.Cancel(id); err != nil {
+		sendJobError(w, err)
+		return
+	}
+
+	job, err := jobManager.Get(id)
+	if err != nil {
+		sendJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "Job cancellation requested", job)
+}
+
+// HandleJobResult downloads the result file of a completed job, or returns its
+// JSON result when the job produced no file
+func HandleJobResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !jobsEnabled(w) {
+		return
+	}
+
+	job, err := jobManager.Get(r.PathValue("id"))
+	if err != nil {
+		sendJobError(w, err)
+		return
+	}
+	if job.Status != jobs.StatusCompleted {
+		sendErrorResponse(w, "Job is "+string(job.Status), http.StatusConflict)
+		return
+	}
+	if job.Result == nil {
+		sendJobError(w, jobs.ErrNoResult)
+		return
+	}
+	if job.Result.Path == "" {
+		sendSuccessResponse(w, "Job result retrieved successfully", job.Result.Data)
+		return
+	}
+
+	// Set headers for file download
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(job.Result.FileName))
+	w.Header().Set("Content-Type", job.Result.ContentType)
+
+	// Send the file
+	http.ServeFile(w, r, job.Result.Path)
+}
+
+// Helper functions
+
+// parseJobUpload parses the form and saves its "carrier" file to a temporary path
+// that the job owns. It sends an error response and returns false on failure.
+func parseJobUpload(w http.ResponseWriter, r *http.Request) (*carrierRequest, bool) {
+	if !jobsEnabled(w) {
+		return nil, false
+	}
+	return anyCarrier.parseUpload(w, r, "job_input_")
+}
+
+// submitJob queues a task that removes its input file when it ends, even if the
+// job is canceled before it starts, and responds with the new job and its status URL
+func submitJob(w http.ResponseWriter, kind, inputPath string, task jobs.Task) {
+	job, err := jobManager.Submit(kind, func(ctx context.Context, progress func(float64)) (*jobs.Result, error) {
+		defer os.Remove(inputPath) // Clean up
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return task(ctx, progress)
+	})
+	if err != nil {
+		os.Remove(inputPath)
+		sendJobError(w, err)
+		return
+	}
+
+	// Send the response
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "Job queued successfully", Data: job})
+}
+
+// jobsEnabled sends an error response and returns false when no job manager is set
+func jobsEnabled(w http.ResponseWriter) bool {
+	if jobManager == nil {
+		sendErrorResponse(w, "Background jobs are not enabled", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// sendJobError sends an error response with the status code matching a job error
+func sendJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound), errors.Is(err, jobs.ErrNoResult):
+		sendErrorResponse(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, jobs.ErrQueueFull), errors.Is(err, jobs.ErrShutdown):
+		sendErrorResponse(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
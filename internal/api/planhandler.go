@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"steganografi/internal/steganography"
+)
+
+// PlanResponse is the planner's recommendation together with the uploaded carrier names
+type PlanResponse struct {
+	Carriers []string `json:"carriers"` // Indexed by the carrier field of each option
+	*steganography.Plan
+}
+
+// HandlePlan recommends the carrier and method to use for a payload
+// The payload is a "message", a "file" or just a "payloadSize" in bytes
+func HandlePlan(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the carriers
+	names, paths, ok := carrierSet.parseUploads(w, r, "plan_")
+	if !ok {
+		return
+	}
+	defer removeFiles(paths) // Clean up
+
+	// Determine the payload size
+	payloadSize, err := planPayloadSize(r)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Plan the embedding
+	plan, err := steganography.PlanEmbedding(paths, payloadSize)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	message := "Plan created successfully"
+	if plan.Best == nil {
+		message = "No carrier can hold the payload"
+	}
+
+	// Send the response
+	sendSuccessResponse(w, message, PlanResponse{Carriers: names, Plan: plan})
+}
+
+// planPayloadSize returns the number of bytes the encoder will be given for the request payload
+func planPayloadSize(r *http.Request) (int, error) {
+	// A file payload is stored with its metadata
+	if file, handler, err := r.FormFile("file"); err == nil {
+		file.Close()
+		return int(handler.Size) + fileMetadataOverhead(handler.Filename, int(handler.Size)), nil
+	}
+
+	if message := r.FormValue("message"); message != "" {
+		return len(message), nil
+	}
+
+	if payloadSizeStr := r.FormValue("payloadSize"); payloadSizeStr != "" {
+		payloadSize, err := strconv.Atoi(payloadSizeStr)
+		if err != nil || payloadSize < 0 {
+			return 0, errors.New("invalid payload size: " + payloadSizeStr)
+		}
+		return payloadSize, nil
+	}
+
+	return 0, errors.New("no payload provided: send a message, a file or a payloadSize")
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"steganografi/internal/steganography"
+)
+
+// ShareManifest is stored in the ZIP returned by the share encoder
+type ShareManifest struct {
+	Carriers []string `json:"carriers"` // Output names, indexed by the carrier field of each piece
+	*steganography.ShareSet
+}
+
+// HandleShareEncode splits a file or message into k-of-n secret shares, one per
+// carrier, and returns the stego carriers in a ZIP archive
+func HandleShareEncode(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the carriers
+	names, inputPaths, ok := carrierSet.parseUploads(w, r, "share_input_")
+	if !ok {
+		return
+	}
+	defer removeFiles(inputPaths) // Clean up
+
+	// Get form values
+	seed := r.FormValue("seed")
+	threshold, err := strconv.Atoi(r.FormValue("threshold"))
+	if err != nil {
+		sendErrorResponse(w, "Invalid threshold", http.StatusBadRequest)
+		return
+	}
+
+	// Prepare the payload with its file metadata
+	payload, ok := formPayload(w, r)
+	if !ok {
+		return
+	}
+
+	// Create the output paths
+	outputPaths, outputNames := carrierOutputs(names, inputPaths)
+	defer removeFiles(outputPaths) // Clean up
+
+	carriers := make([]steganography.SplitCarrier, len(inputPaths))
+	for i, inputPath := range inputPaths {
+		carriers[i] = steganography.SplitCarrier{InputPath: inputPath, OutputPath: outputPaths[i]}
+	}
+
+	// Share the payload across the carriers
+	set, err := steganography.ShareEncodeContext(r.Context(), seed, carriers, payload, threshold, nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to share payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Send the stego carriers with the manifest
+	w.Header().Set("X-Share-Set-ID", set.SetID)
+	sendCarrierArchive(w, outputNames, outputPaths, ShareManifest{Carriers: outputNames, ShareSet: set})
+}
+
+// HandleShareDecode reconstructs a payload from any threshold number of share carriers
+// When too few shares are found it reports them instead of the file
+func HandleShareDecode(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the carriers
+	names, paths, ok := carrierSet.parseUploads(w, r, "share_decode_")
+	if !ok {
+		return
+	}
+	defer removeFiles(paths) // Clean up
+
+	// Get form values
+	seed := r.FormValue("seed")
+
+	// Combine the shares
+	recovery, err := steganography.ShareDecodeContext(r.Context(), seed, paths, nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to decode carriers: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := map[string]interface{}{
+		"carriers": names,
+		"recovery": recovery,
+	}
+
+	if !recovery.Verified {
+		message := "Secret hash mismatch"
+		if len(recovery.Pieces) < recovery.Threshold {
+			message = "Found " + strconv.Itoa(len(recovery.Pieces)) + " shares, " + strconv.Itoa(recovery.Threshold) + " are needed"
+		}
+		sendIncompleteResponse(w, message, report)
+		return
+	}
+
+	// Send the file with the report
+	sendRecoveredFile(w, "Secret reconstructed successfully", recovery.Data, report)
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"steganografi/internal/steganography"
+)
+
+// SplitManifest is stored in the ZIP returned by the split encoder
+type SplitManifest struct {
+	Carriers []string `json:"carriers"` // Output names, indexed by the carrier field of each piece
+	*steganography.SplitSet
+}
+
+// HandleSplitEncode splits a file or message across several carriers and returns
+// the stego carriers in a ZIP archive
+func HandleSplitEncode(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the carriers
+	names, inputPaths, ok := carrierSet.parseUploads(w, r, "split_input_")
+	if !ok {
+		return
+	}
+	defer removeFiles(inputPaths) // Clean up
+
+	// Get form values
+	seed := r.FormValue("seed")
+
+	// Prepare the payload with its file metadata
+	payload, ok := formPayload(w, r)
+	if !ok {
+		return
+	}
+
+	// Create the output paths
+	outputPaths, outputNames := carrierOutputs(names, inputPaths)
+	defer removeFiles(outputPaths) // Clean up
+
+	carriers := make([]steganography.SplitCarrier, len(inputPaths))
+	for i, inputPath := range inputPaths {
+		carriers[i] = steganography.SplitCarrier{InputPath: inputPath, OutputPath: outputPaths[i]}
+	}
+
+	// Split the payload across the carriers
+	set, err := steganography.SplitEncodeContext(r.Context(), seed, carriers, payload, nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to split payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Send the stego carriers with the manifest
+	w.Header().Set("X-Split-Set-ID", set.SetID)
+	sendCarrierArchive(w, outputNames, outputPaths, SplitManifest{Carriers: outputNames, SplitSet: set})
+}
+
+// HandleSplitDecode reassembles a payload from carriers given in any order
+// When pieces are missing it reports them instead of the file
+func HandleSplitDecode(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and save the carriers
+	names, paths, ok := carrierSet.parseUploads(w, r, "split_decode_")
+	if !ok {
+		return
+	}
+	defer removeFiles(paths) // Clean up
+
+	// Get form values
+	seed := r.FormValue("seed")
+
+	// Reassemble the payload
+	recovery, err := steganography.SplitDecodeContext(r.Context(), seed, paths, nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to decode carriers: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := map[string]interface{}{
+		"carriers": names,
+		"recovery": recovery,
+	}
+
+	if !recovery.Verified {
+		message := "Payload hash mismatch"
+		if len(recovery.Missing) > 0 {
+			message = "Missing " + strconv.Itoa(len(recovery.Missing)) + " of " + strconv.Itoa(recovery.Total) + " pieces"
+		}
+
+		sendIncompleteResponse(w, message, report)
+		return
+	}
+
+	// Send the file with the report
+	sendRecoveredFile(w, "Payload reassembled successfully", recovery.Data, report)
+}
+
+// formPayload packs the "file" or "message" of a parsed multipart form with its file metadata
+// A message is stored as message.txt. It sends an error response and returns false on failure
+func formPayload(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	var fileData []byte
+	var fileName string
+	if file, handler, err := r.FormFile("file"); err == nil {
+		fileData, err = io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			sendErrorResponse(w, "Failed to read data file", http.StatusBadRequest)
+			return nil, false
+		}
+		fileName = handler.Filename
+	} else if message := r.FormValue("message"); message != "" {
+		fileData, fileName = []byte(message), "message.txt"
+	} else {
+		sendErrorResponse(w, "No payload provided: send a message or a file", http.StatusBadRequest)
+		return nil, false
+	}
+
+	payload, err := steganography.PackFileData(fileData, fileName)
+	if err != nil {
+		sendErrorResponse(w, "Failed to create file metadata", http.StatusInternalServerError)
+		return nil, false
+	}
+	return payload, true
+}
+
+// carrierOutputs returns the output paths and download names for saved carriers
+// Image carriers are written as PNG; other carriers keep their format
+func carrierOutputs(names, inputPaths []string) ([]string, []string) {
+	outputPaths := make([]string, len(inputPaths))
+	outputNames := make([]string, len(inputPaths))
+	for i, inputPath := range inputPaths {
+		ext := filepath.Ext(inputPath)
+		if !isSupportedAudioExt(ext) && !isSupportedVideoExt(ext) {
+			ext = ".png"
+		}
+		outputPaths[i] = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + "_output" + ext
+		outputNames[i] = "stego_" + strconv.Itoa(i+1) + "_" + strings.TrimSuffix(filepath.Base(names[i]), filepath.Ext(names[i])) + ext
+	}
+	return outputPaths, outputNames
+}
+
+// sendCarrierArchive sends the output carriers and a manifest.json in a ZIP archive
+func sendCarrierArchive(w http.ResponseWriter, outputNames, outputPaths []string, manifest any) {
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	for i, outputPath := range outputPaths {
+		if err := addFileToZip(zipWriter, outputNames[i], outputPath); err != nil {
+			sendErrorResponse(w, "Failed to create archive", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		sendErrorResponse(w, "Failed to create archive", http.StatusInternalServerError)
+		return
+	}
+	entry, err := zipWriter.Create("manifest.json")
+	if err == nil {
+		_, err = entry.Write(manifestJSON)
+	}
+	if err == nil {
+		err = zipWriter.Close()
+	}
+	if err != nil {
+		sendErrorResponse(w, "Failed to create archive", http.StatusInternalServerError)
+		return
+	}
+
+	// Set headers for file download
+	w.Header().Set("Content-Disposition", "attachment; filename=stego_carriers.zip")
+	w.Header().Set("Content-Type", "application/zip")
+
+	// Send the archive
+	w.Write(archive.Bytes())
+}
+
+// sendRecoveredFile unpacks a recovered payload and sends the file with a report
+func sendRecoveredFile(w http.ResponseWriter, message string, payload []byte, report map[string]interface{}) {
+	// Extract the file and its metadata
+	metadata, fileData, err := steganography.ExtractFileData(payload)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report["fileName"] = metadata.FileName
+	report["fileExt"] = metadata.FileExt
+	report["fileSize"] = metadata.FileSize
+	report["fileData"] = base64.StdEncoding.EncodeToString(fileData)
+
+	// Send the response
+	sendSuccessResponse(w, message, report)
+}
+
+// sendIncompleteResponse reports a recovery that could not produce the payload
+func sendIncompleteResponse(w http.ResponseWriter, message string, report any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(Response{Success: false, Message: message, Data: report})
+}
+
+// addFileToZip copies a file into a ZIP archive under the given name
+func addFileToZip(zipWriter *zip.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entry, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entry, file)
+	return err
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"steganografi/internal/steganography"
+)
+
+// HandleWatermarkEmbed embeds a robust watermark ID into an image
+func HandleWatermarkEmbed(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and decode the image
+	img, ok := imageCarrier.parseImage(w, r)
+	if !ok {
+		return
+	}
+
+	// Get form values
+	id := r.FormValue("id")
+	encoder, ok := formWatermarkEncoder(w, r)
+	if !ok {
+		return
+	}
+
+	// Embed the watermark
+	marked, err := encoder.EmbedImageContext(r.Context(), img, []byte(id), nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to embed watermark: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sendPNG(w, marked, "watermarked_image.png")
+}
+
+// HandleWatermarkExtract reads a robust watermark ID from an image
+func HandleWatermarkExtract(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and decode the image
+	img, ok := imageCarrier.parseImage(w, r)
+	if !ok {
+		return
+	}
+
+	// Get form values
+	encoder, ok := formWatermarkEncoder(w, r)
+	if !ok {
+		return
+	}
+
+	// Extract the watermark
+	id, err := encoder.ExtractImageContext(r.Context(), img, nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to extract watermark: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Send the response
+	sendSuccessResponse(w, "Watermark extracted successfully", map[string]string{
+		"id":    string(id),
+		"idHex": hex.EncodeToString(id),
+	})
+}
+
+// HandleWatermarkTest embeds a watermark ID and reports whether it survives
+// JPEG quality 75, a 50% downscale and both combined
+func HandleWatermarkTest(w http.ResponseWriter, r *http.Request) {
+	// Parse the form and decode the image
+	img, ok := imageCarrier.parseImage(w, r)
+	if !ok {
+		return
+	}
+
+	// Get form values
+	id := r.FormValue("id")
+	encoder, ok := formWatermarkEncoder(w, r)
+	if !ok {
+		return
+	}
+
+	// Run the attacks
+	results, err := encoder.TestRobustnessContext(r.Context(), img, []byte(id), steganography.StandardWatermarkAttacks(), nil)
+	if err != nil {
+		sendErrorResponse(w, "Failed to test watermark: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Send the response
+	sendSuccessResponse(w, "Robustness test completed successfully", results)
+}
+
+// formWatermarkEncoder creates a watermark encoder from the "seed" and "strength" form values
+// It sends an error response and returns false on failure
+func formWatermarkEncoder(w http.ResponseWriter, r *http.Request) (*steganography.WatermarkEncoder, bool) {
+	strength := 0.0 // Encoder default
+	if strengthStr := r.FormValue("strength"); strengthStr != "" {
+		var err error
+		strength, err = strconv.ParseFloat(strengthStr, 64)
+		if err != nil {
+			sendErrorResponse(w, "Invalid strength: "+strengthStr, http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	encoder, err := steganography.NewWatermarkEncoder(r.FormValue("seed"), strength)
+	if err != nil {
+		sendErrorResponse(w, "Failed to create encoder: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return encoder, true
+}
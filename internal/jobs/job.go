@@ -0,0 +1,75 @@
+// Package jobs runs long steganography tasks in the background with a bounded
+// worker pool, and keeps their status, progress and results in a store.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Status is the state of a job
+type Status string
+
+// Job states
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Errors returned by the manager and stores
+var (
+	ErrNotFound  = errors.New("job not found")
+	ErrQueueFull = errors.New("job queue is full")
+	ErrNoResult  = errors.New("job has no result")
+	ErrShutdown  = errors.New("job manager is shut down")
+)
+
+// Job is the status of a background task
+type Job struct {
+	ID       string    `json:"id"`
+	Kind     string    `json:"kind"` // What the job does, e.g. "encode"
+	Status   Status    `json:"status"`
+	Progress float64   `json:"progress"` // Percentage from 0 to 100
+	Error    string    `json:"error,omitempty"`
+	Result   *Result   `json:"result,omitempty"` // Set once the job completes
+	Created  time.Time `json:"created"`
+	Started  time.Time `json:"started,omitzero"`
+	Finished time.Time `json:"finished,omitzero"`
+}
+
+// Result is the output of a completed job: a downloadable file, a JSON value, or both
+type Result struct {
+	FileName    string `json:"fileName,omitempty"` // Download name of the result file
+	ContentType string `json:"contentType,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	Path        string `json:"-"`              // Result file, owned by the store
+	Data        any    `json:"data,omitempty"` // JSON result, e.g. a decoded message
+}
+
+// Task does the work of a job. It should stop when ctx is canceled and may report
+// its progress as a fraction from 0 to 1. A result file at Result.Path is handed
+// over to the store. Every task is called exactly once, with an already canceled
+// context if its job was canceled while queued, so it can release what it holds.
+type Task func(ctx context.Context, progress func(fraction float64)) (*Result, error)
+
+// Done reports whether a job has finished, successfully or not
+func (j *Job) Done() bool {
+	return j.Status == StatusCompleted || j.Status == StatusFailed || j.Status == StatusCanceled
+}
+
+// Helper functions
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
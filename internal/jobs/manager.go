@@ -0,0 +1,355 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// Manager defaults
+const (
+	DefaultWorkers   = 2
+	DefaultQueueSize = 32
+	DefaultRetention = time.Hour // How long finished jobs and their results are kept
+)
+
+// progressStep is the smallest progress change, in percent, that is written to the store
+const progressStep = 1.0
+
+// Manager runs submitted tasks on a bounded pool of workers
+type Manager struct {
+	store     Store
+	queue     chan queuedTask
+	retention time.Duration
+
+	// mu guards cancels and closed, and serializes every read-modify-write of a
+	// stored job so status and progress updates never overwrite each other
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc // Running and queued jobs
+	closed  bool
+
+	ctx  context.Context // Canceled on shutdown
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// queuedTask is a task waiting for a worker
+type queuedTask struct {
+	id   string
+	ctx  context.Context
+	task Task
+}
+
+// NewManager starts a manager with the given number of workers and queue size.
+// Values of zero or less use the defaults, and a negative retention keeps finished
+// jobs until they are deleted.
+func NewManager(store Store, workers, queueSize int, retention time.Duration) *Manager {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	if retention == 0 {
+		retention = DefaultRetention
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	m := &Manager{
+		store:     store,
+		queue:     make(chan queuedTask, queueSize),
+		retention: retention,
+		cancels:   make(map[string]context.CancelFunc),
+		ctx:       ctx,
+		stop:      stop,
+	}
+
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	if retention > 0 {
+		m.wg.Add(1)
+		go m.janitor()
+	}
+
+	return m
+}
+
+// Submit queues a task and returns its job. It fails with ErrQueueFull when
+// every worker is busy and the queue is full.
+func (m *Manager) Submit(kind string, task Task) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{ID: id, Kind: kind, Status: StatusQueued, Created: time.Now()}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Job{}, ErrShutdown
+	}
+
+	if err := m.store.Put(job); err != nil {
+		return Job{}, err
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	select {
+	case m.queue <- queuedTask{id: id, ctx: ctx, task: task}:
+		m.cancels[id] = cancel
+		return job, nil
+	default:
+		cancel()
+		m.store.Delete(id)
+		return Job{}, ErrQueueFull
+	}
+}
+
+// Get returns the current state of a job
+func (m *Manager) Get(id string) (Job, error) {
+	return m.store.Get(id)
+}
+
+// List returns all jobs, oldest first
+func (m *Manager) List() ([]Job, error) {
+	return m.store.List()
+}
+
+// Cancel stops a queued or running job. Canceling a finished job does nothing.
+func (m *Manager) Cancel(id string) error {
+	return m.update(id, func(job *Job) bool {
+		if cancel, ok := m.cancels[id]; ok {
+			cancel()
+		}
+
+		// A queued job is marked right away; a running one when its task returns
+		if job.Status != StatusQueued {
+			return false
+		}
+		job.Status = StatusCanceled
+		job.Error = context.Canceled.Error()
+		job.Finished = time.Now()
+		return true
+	})
+}
+
+// Delete cancels a job if it is still active and removes it with its result.
+// Updates from its task that arrive afterwards are dropped.
+func (m *Manager) Delete(id string) error {
+	if err := m.Cancel(id); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store.Delete(id)
+}
+
+// Shutdown stops accepting jobs, cancels the active ones and waits for the
+// workers to finish or for ctx to be done
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+	m.stop()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Helper functions
+
+// worker runs queued tasks until the queue is closed
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for queued := range m.queue {
+		m.run(queued)
+	}
+}
+
+// run executes one task and records its outcome
+func (m *Manager) run(queued queuedTask) {
+	defer func() {
+		m.mu.Lock()
+		if cancel, ok := m.cancels[queued.id]; ok {
+			cancel()
+			delete(m.cancels, queued.id)
+		}
+		m.mu.Unlock()
+	}()
+
+	started := false
+	err := m.update(queued.id, func(job *Job) bool {
+		if job.Status != StatusQueued || queued.ctx.Err() != nil {
+			return false
+		}
+		job.Status = StatusRunning
+		job.Started = time.Now()
+		started = true
+		return true
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Println("jobs: failed to save job " + queued.id + ": " + err.Error())
+	}
+	if !started {
+		// Deleted or canceled while queued: let the task release what it holds
+		ctx, cancel := context.WithCancel(queued.ctx)
+		cancel()
+		if result, _ := runTask(ctx, queued.task, func(float64) {}); result != nil && result.Path != "" {
+			os.Remove(result.Path)
+		}
+		m.finish(queued.id, nil, ctx.Err())
+		return
+	}
+
+	// Write progress to the store in steps, so a chatty task does not flood it
+	var progressMu sync.Mutex
+	reported := 0.0
+	progress := func(fraction float64) {
+		percent := math.Max(0, math.Min(100, fraction*100))
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		if percent-reported < progressStep || percent >= 100 {
+			return
+		}
+		reported = percent
+
+		// Only a running job takes progress, so a late write cannot undo a
+		// cancel or bring back a deleted job
+		m.update(queued.id, func(job *Job) bool {
+			if job.Status != StatusRunning {
+				return false
+			}
+			job.Progress = math.Floor(percent)
+			return true
+		})
+	}
+
+	result, err := runTask(queued.ctx, queued.task, progress)
+	m.finish(queued.id, result, err)
+}
+
+// finish records the result or error of a job. A job that was deleted or has
+// already finished is left alone, and its result file is removed.
+func (m *Manager) finish(id string, result *Result, err error) {
+	kept := false
+	saveErr := m.update(id, func(job *Job) bool {
+		if job.Done() {
+			return false
+		}
+
+		job.Finished = time.Now()
+		switch {
+		case errors.Is(err, context.Canceled):
+			job.Status = StatusCanceled
+			job.Error = err.Error()
+		case err != nil:
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		default:
+			job.Status = StatusCompleted
+			job.Progress = 100
+			job.Result = result
+		}
+
+		// Hand the result file over to the store
+		if result != nil && result.Path != "" && job.Status == StatusCompleted {
+			if path, err := m.store.TakeResult(job.ID, result.Path); err != nil {
+				job.Status = StatusFailed
+				job.Error = "failed to store result: " + err.Error()
+				job.Result = nil
+			} else {
+				kept = true
+				result.Path = path
+				if info, err := os.Stat(path); err == nil {
+					result.Size = info.Size()
+				}
+			}
+		}
+		return true
+	})
+	if saveErr != nil && !errors.Is(saveErr, ErrNotFound) {
+		log.Println("jobs: failed to save job " + id + ": " + saveErr.Error())
+	}
+
+	if !kept && result != nil && result.Path != "" {
+		os.Remove(result.Path)
+	}
+}
+
+// update applies change to a stored job and saves it while holding m.mu.
+// The job is not saved when change returns false.
+func (m *Manager) update(id string, change func(job *Job) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if !change(&job) {
+		return nil
+	}
+	return m.store.Put(job)
+}
+
+// runTask runs a task, turning a panic into an error so one bad job cannot stop a worker
+func runTask(ctx context.Context, task Task, progress func(fraction float64)) (result *Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, errors.New("job panicked")
+			log.Println("jobs: task panicked:", r)
+		}
+	}()
+
+	result, err = task(ctx, progress)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return result, err
+}
+
+// janitor removes finished jobs older than the retention period
+func (m *Manager) janitor() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(min(m.retention, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			jobs, err := m.store.List()
+			if err != nil {
+				continue
+			}
+			for _, job := range jobs {
+				if job.Done() && time.Since(job.Finished) > m.retention {
+					m.mu.Lock()
+					m.store.Delete(job.ID)
+					m.mu.Unlock()
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitDone polls a job until it has finished and returns its final state
+func waitDone(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if job.Done() {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+// slowStore pauses after reading a running job, widening the window in which a
+// read-modify-write of its status could race with Cancel or Delete
+type slowStore struct {
+	*MemoryStore
+}
+
+func (s slowStore) Get(id string) (Job, error) {
+	job, err := s.MemoryStore.Get(id)
+	if err == nil && job.Status == StatusRunning {
+		time.Sleep(time.Millisecond)
+	}
+	return job, err
+}
+
+// chattyTask reports progress in a loop, keeps reporting for a while after it
+// is canceled, and closes exited when it returns
+func chattyTask(started, exited chan struct{}) Task {
+	return func(ctx context.Context, progress func(float64)) (*Result, error) {
+		defer close(exited)
+		close(started)
+		for i := 0; ctx.Err() == nil; i++ {
+			progress(float64(i%100) / 100)
+		}
+		for i := 0; i < 1000; i++ {
+			progress(float64(i%100) / 100)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func TestManagerCancelRunningJob(t *testing.T) {
+	m := NewManager(slowStore{NewMemoryStore()}, 1, 1, -1)
+	defer m.Shutdown(context.Background())
+
+	started, exited := make(chan struct{}), make(chan struct{})
+	job, err := m.Submit("test", chattyTask(started, exited))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-started
+
+	if err := m.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	<-exited
+
+	// Late progress writes must not turn the job back to running
+	job = waitDone(t, m, job.ID)
+	time.Sleep(10 * time.Millisecond)
+	if job, _ = m.Get(job.ID); job.Status != StatusCanceled {
+		t.Errorf("status = %s, want %s", job.Status, StatusCanceled)
+	}
+}
+
+func TestManagerDeleteRunningJob(t *testing.T) {
+	m := NewManager(slowStore{NewMemoryStore()}, 1, 1, -1)
+	defer m.Shutdown(context.Background())
+
+	started, exited := make(chan struct{}), make(chan struct{})
+	job, err := m.Submit("test", chattyTask(started, exited))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-started
+
+	if err := m.Delete(job.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	<-exited
+
+	// Neither progress nor the task's outcome may bring the job back
+	time.Sleep(10 * time.Millisecond)
+	if _, err := m.Get(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestManagerQueueFull(t *testing.T) {
+	m := NewManager(NewMemoryStore(), 1, 1, -1)
+	defer m.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	blocking := func(ctx context.Context, progress func(float64)) (*Result, error) {
+		<-release
+		return &Result{Data: "done"}, nil
+	}
+
+	// One job runs, one waits in the queue, and the third is turned away
+	running, err := m.Submit("test", blocking)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	for {
+		if job, _ := m.Get(running.ID); job.Status == StatusRunning {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	queued, err := m.Submit("test", blocking)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if _, err := m.Submit("test", blocking); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Submit on a full queue: err = %v, want %v", err, ErrQueueFull)
+	}
+
+	// A queued job is canceled right away, and its task still runs once
+	if err := m.Cancel(queued.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if job, _ := m.Get(queued.ID); job.Status != StatusCanceled {
+		t.Errorf("queued job status = %s, want %s", job.Status, StatusCanceled)
+	}
+
+	close(release)
+	if job := waitDone(t, m, running.ID); job.Status != StatusCompleted || job.Result.Data != "done" {
+		t.Errorf("running job = %+v, want completed", job)
+	}
+	if job := waitDone(t, m, queued.ID); job.Status != StatusCanceled {
+		t.Errorf("queued job status = %s, want %s", job.Status, StatusCanceled)
+	}
+}
+
+func TestManagerShutdown(t *testing.T) {
+	m := NewManager(NewMemoryStore(), 1, 1, -1)
+
+	started := make(chan struct{})
+	job, err := m.Submit("test", func(ctx context.Context, progress func(float64)) (*Result, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if job, _ = m.Get(job.ID); job.Status != StatusCanceled {
+		t.Errorf("status = %s, want %s", job.Status, StatusCanceled)
+	}
+	if _, err := m.Submit("test", nil); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Submit after Shutdown: err = %v, want %v", err, ErrShutdown)
+	}
+}
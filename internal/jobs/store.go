@@ -0,0 +1,267 @@
+package jobs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store keeps jobs and their result files
+type Store interface {
+	// Put creates or replaces a job
+	Put(job Job) error
+	// Get returns a job by ID, or ErrNotFound
+	Get(id string) (Job, error)
+	// List returns all jobs, oldest first
+	List() ([]Job, error)
+	// Delete removes a job and its result file
+	Delete(id string) error
+	// TakeResult moves a result file into the store and returns its new path
+	TakeResult(id, path string) (string, error)
+}
+
+// MemoryStore keeps jobs in memory and result files where the tasks wrote them
+// Jobs are lost when the process exits.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	jobs map[string]Job
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]Job)}
+}
+
+// Put creates or replaces a job
+func (s *MemoryStore) Put(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+// Get returns a job by ID
+func (s *MemoryStore) Get(id string) (Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return job, nil
+}
+
+// List returns all jobs, oldest first
+func (s *MemoryStore) List() ([]Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// Delete removes a job and its result file
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	delete(s.jobs, id)
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+	removeResult(job)
+	return nil
+}
+
+// TakeResult leaves the result file in place
+func (s *MemoryStore) TakeResult(id, path string) (string, error) {
+	return path, nil
+}
+
+// DiskStore keeps every job as a JSON file next to its result file in a directory,
+// so jobs and results survive a restart
+type DiskStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// jobIDPattern matches the IDs the manager creates, which keeps file names safe
+var jobIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// NewDiskStore opens a store in a directory, creating it if needed. Jobs that were
+// queued or running when the previous process stopped are marked as failed.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &DiskStore{dir: dir}
+
+	jobs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if !job.Done() {
+			job.Status = StatusFailed
+			job.Error = "interrupted by a server restart"
+			job.Finished = time.Now()
+			if err := s.Put(job); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Put creates or replaces a job
+func (s *DiskStore) Put(job Job) error {
+	if !jobIDPattern.MatchString(job.ID) {
+		return ErrNotFound
+	}
+
+	data, err := json.Marshal(diskJob{Job: job, ResultPath: resultPath(job)})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Write to a temporary file first, so a crash never leaves a partial job
+	tempPath := s.jobPath(job.ID) + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, s.jobPath(job.ID))
+}
+
+// Get returns a job by ID
+func (s *DiskStore) Get(id string) (Job, error) {
+	if !jobIDPattern.MatchString(id) {
+		return Job{}, ErrNotFound
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.read(s.jobPath(id))
+}
+
+// List returns all jobs, oldest first
+func (s *DiskStore) List() ([]Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []Job
+	for _, path := range paths {
+		if job, err := s.read(path); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// Delete removes a job and its result file
+func (s *DiskStore) Delete(id string) error {
+	job, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeResult(job)
+	return os.Remove(s.jobPath(id))
+}
+
+// TakeResult moves a result file into the store directory
+func (s *DiskStore) TakeResult(id, path string) (string, error) {
+	if !jobIDPattern.MatchString(id) {
+		return "", ErrNotFound
+	}
+
+	target := filepath.Join(s.dir, id+".result")
+	if err := os.Rename(path, target); err != nil {
+		// Fall back to copying when the file is on another file system
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return "", err
+		}
+		os.Remove(path)
+	}
+	return target, nil
+}
+
+// Helper functions
+
+// diskJob is the JSON form of a job on disk, which also records its result file
+type diskJob struct {
+	Job
+	ResultPath string `json:"resultPath,omitempty"`
+}
+
+// jobPath returns the path of a job's JSON file
+func (s *DiskStore) jobPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// read loads a job from its JSON file
+func (s *DiskStore) read(path string) (Job, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Job{}, ErrNotFound
+	}
+	if err != nil {
+		return Job{}, err
+	}
+
+	var stored diskJob
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return Job{}, err
+	}
+	if stored.Result != nil {
+		stored.Result.Path = stored.ResultPath
+	}
+	if !jobIDPattern.MatchString(stored.ID) || !strings.HasPrefix(filepath.Base(path), stored.ID) {
+		return Job{}, ErrNotFound
+	}
+	return stored.Job, nil
+}
+
+// resultPath returns the path of a job's result file, if any
+func resultPath(job Job) string {
+	if job.Result == nil {
+		return ""
+	}
+	return job.Result.Path
+}
+
+// removeResult deletes a job's result file
+func removeResult(job Job) {
+	if path := resultPath(job); path != "" {
+		os.Remove(path)
+	}
+}
+
+// sortJobs orders jobs by creation time
+func sortJobs(jobs []Job) {
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].Created.Before(jobs[b].Created) })
+}
@@ -0,0 +1,199 @@
+// aiff.go - AIFF and AIFF-C container handling
+package steganography
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// readAiffFile reads an AIFF or AIFF-C file and preserves all of its chunks
+func readAiffFile(f io.Reader) (*audioCarrier, error) {
+	// Read FORM header
+	var formID [4]byte
+	var formSize uint32
+	var formType [4]byte
+
+	if err := binary.Read(f, binary.BigEndian, &formID); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(f, binary.BigEndian, &formSize); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(f, binary.BigEndian, &formType); err != nil {
+		return nil, err
+	}
+
+	carrier := &audioCarrier{BigEndian: true}
+	switch string(formType[:]) {
+	case "AIFF":
+		carrier.Format = "aiff"
+	case "AIFC":
+		carrier.Format = "aifc"
+	default:
+		return nil, errors.New("not a valid AIFF file")
+	}
+
+	var commFound, ssndFound bool
+	for {
+		var chunkID [4]byte
+		var chunkSize uint32
+
+		if err := binary.Read(f, binary.BigEndian, &chunkID); err != nil {
+			if ssndFound {
+				break
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, errors.New("AIFF file has no SSND chunk")
+			}
+			return nil, err
+		}
+		if err := binary.Read(f, binary.BigEndian, &chunkSize); err != nil {
+			if ssndFound {
+				break
+			}
+			return nil, err
+		}
+
+		id := string(chunkID[:])
+		body, err := readChunkBody(f, uint64(chunkSize))
+		if err != nil {
+			if ssndFound {
+				break // Drop a truncated trailing chunk
+			}
+			if id != "SSND" || err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+		}
+
+		switch id {
+		case "COMM":
+			if len(body) < 18 {
+				return nil, errors.New("invalid COMM chunk")
+			}
+			commFound = true
+			carrier.Channels = int(binary.BigEndian.Uint16(body[0:2]))
+			carrier.BitsPerSample = int(binary.BigEndian.Uint16(body[6:8]))
+			carrier.SampleRate = int(extendedToFloat64(body[8:18]))
+
+			if carrier.Format == "aifc" {
+				if len(body) < 22 {
+					return nil, errors.New("invalid AIFF-C COMM chunk")
+				}
+				switch string(body[18:22]) {
+				case "NONE", "twos":
+					// Big-endian PCM
+				case "sowt":
+					carrier.BigEndian = false
+				default:
+					return nil, errors.New("unsupported AIFF-C compression: " + string(body[18:22]))
+				}
+			}
+		case "SSND":
+			if ssndFound {
+				return nil, errors.New("AIFF file has more than one SSND chunk")
+			}
+			if len(body) < 8 {
+				return nil, errors.New("invalid SSND chunk")
+			}
+			// Samples start after the offset and block size fields plus the offset itself
+			start := 8 + int(binary.BigEndian.Uint32(body[0:4]))
+			if start > len(body) {
+				return nil, errors.New("invalid SSND chunk offset")
+			}
+			ssndFound = true
+			carrier.Samples = body[start:]
+			body = body[:start]
+		}
+
+		carrier.Chunks = append(carrier.Chunks, audioChunk{ID: id, Data: body})
+		if err != nil {
+			break // Truncated SSND chunk
+		}
+
+		// Skip the pad byte of odd-sized chunks
+		if chunkSize%2 == 1 {
+			var pad [1]byte
+			if _, err := io.ReadFull(f, pad[:]); err != nil {
+				break
+			}
+		}
+	}
+
+	if !commFound {
+		return nil, errors.New("AIFF file has no COMM chunk")
+	}
+
+	return carrier, nil
+}
+
+// writeAiffFile writes a carrier as an AIFF or AIFF-C file
+func writeAiffFile(w io.Writer, carrier *audioCarrier) error {
+	// Compute the total FORM size
+	var formSize uint64 = 4 // AIFF/AIFC
+	for _, chunk := range carrier.Chunks {
+		size := uint64(len(chunk.Data))
+		if chunk.ID == "SSND" {
+			size += uint64(len(carrier.Samples))
+		}
+		formSize += 8 + size + size%2
+	}
+	if formSize > math.MaxUint32 {
+		return errors.New("audio data exceeds the 4 GB AIFF size limit")
+	}
+
+	// Write FORM header
+	io.WriteString(w, "FORM")
+	binary.Write(w, binary.BigEndian, uint32(formSize))
+	if carrier.Format == "aifc" {
+		io.WriteString(w, "AIFC")
+	} else {
+		io.WriteString(w, "AIFF")
+	}
+
+	// Write chunks in their original order
+	for _, chunk := range carrier.Chunks {
+		size := len(chunk.Data)
+		if chunk.ID == "SSND" {
+			size += len(carrier.Samples)
+		}
+
+		io.WriteString(w, chunk.ID)
+		binary.Write(w, binary.BigEndian, uint32(size))
+
+		if _, err := w.Write(chunk.Data); err != nil {
+			return err
+		}
+		if chunk.ID == "SSND" {
+			if _, err := w.Write(carrier.Samples); err != nil {
+				return err
+			}
+		}
+		if size%2 == 1 {
+			if _, err := w.Write([]byte{0}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// extendedToFloat64 converts an 80-bit IEEE 754 extended precision number
+// (used for the AIFF sample rate) to a float64
+func extendedToFloat64(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:2]))
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+
+	sign := 1.0
+	if exponent&0x8000 != 0 {
+		sign = -1.0
+		exponent &= 0x7FFF
+	}
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+
+	return sign * math.Ldexp(float64(mantissa), exponent-16383-63)
+}
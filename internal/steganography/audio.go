@@ -2,52 +2,67 @@
 package steganography
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
-	"io"
 	"math/rand"
-	"os"
-	"strconv"
 )
 
-// AudioEncoder handles LSB steganography for WAV files
+// AudioEncoder handles LSB steganography for WAV, RF64 and AIFF files
 type AudioEncoder struct {
-	Seed int64
+	Seed     int64
+	Mode     AudioMode // Where to store the payload (LSB by default)
+	Password string    // Optional password used to encrypt the payload
 }
 
 // NewAudioEncoder creates a new audio steganography encoder with the given seed
 func NewAudioEncoder(seed string) (*AudioEncoder, error) {
-	var seedInt int64 = -1 // Default to -1 (sequential)
-
-	if seed != "" {
-		// Convert seed string to int64
-		var err error
-		seedInt, err = strconv.ParseInt(seed, 10, 64)
-		if err != nil {
-			// If not a number, use string hash as seed
-			h := 0
-			for i := 0; i < len(seed); i++ {
-				h = 31*h + int(seed[i])
-			}
-			seedInt = int64(h)
-		}
-	}
+	seedInt := parseSeed(seed)
 
 	return &AudioEncoder{
 		Seed: seedInt,
 	}, nil
 }
 
-// EncodeData embeds binary data into a WAV file using LSB steganography
+// EncodeData embeds binary data into an audio file using LSB steganography
 func (e *AudioEncoder) EncodeData(inputPath, outputPath string, data []byte) error {
-	// Read WAV file
-	header, audioData, err := readWavFile(inputPath)
+	return e.EncodeDataContext(context.Background(), inputPath, outputPath, data, nil)
+}
+
+// EncodeDataContext is EncodeData with cancellation and progress reporting
+func (e *AudioEncoder) EncodeDataContext(ctx context.Context, inputPath, outputPath string, data []byte, progress Progress) error {
+	t := newTracker(ctx, progress)
+
+	// Read audio file
+	carrier, err := readAudioFile(inputPath)
 	if err != nil {
 		return err
 	}
+	if err := t.report(0.2); err != nil {
+		return err
+	}
+
+	// Container modes store the payload in a metadata chunk instead of the samples
+	if e.Mode != "" && e.Mode != AudioModeLSB {
+		if err := e.embedInContainer(carrier, data); err != nil {
+			return err
+		}
+		if err := writeAudioFile(outputPath, carrier); err != nil {
+			return err
+		}
+		return t.report(1)
+	}
+
+	// Encrypt the payload if a password is set
+	if e.Password != "" {
+		data, err = EncryptData(data, e.Password)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Calculate capacity (1 bit per sample)
-	capacityBits := len(audioData)
+	capacityBits := carrier.sampleCount()
 	messageBits := len(data)*8 + 32 // 32 bits for length
 
 	if messageBits > capacityBits {
@@ -60,64 +75,113 @@ func (e *AudioEncoder) EncodeData(inputPath, outputPath string, data []byte) err
 	copy(fullData[4:], data)
 
 	// Generate sample indices based on seed
-	indices := generateSampleOrder(len(audioData), len(fullData)*8, e.Seed)
+	indices, err := generateSampleOrder(t.span(0.2, 0.7), carrier.sampleCount(), len(fullData)*8, e.Seed)
+	if err != nil {
+		return err
+	}
 
 	// Embed data
+	if err := t.phase(0.7, 0.8, len(fullData)); err != nil {
+		return err
+	}
 	bitIndex := 0
 	for i := 0; i < len(fullData); i++ {
+		if err := t.advance(1); err != nil {
+			return err
+		}
 		byteVal := fullData[i]
 		for b := 0; b < 8; b++ {
 			bit := (byteVal >> (7 - b)) & 1
-			sampleIndex := indices[bitIndex]
+			offset := carrier.lsbOffset(indices[bitIndex])
 			// Clear LSB and set to message bit
-			audioData[sampleIndex] = (audioData[sampleIndex] & 0xFE) | bit
+			carrier.Samples[offset] = (carrier.Samples[offset] & 0xFE) | bit
 			bitIndex++
 		}
 	}
 
-	// Write modified WAV file
-	return writeWavFile(outputPath, header, audioData)
+	// Write modified audio file in its original format
+	if err := writeAudioFile(outputPath, carrier); err != nil {
+		return err
+	}
+	return t.report(1)
+}
+
+// Capacity returns the largest payload in bytes that EncodeData can hide in the audio file
+// Container modes are not limited by the audio and return UnlimitedCapacity
+func (e *AudioEncoder) Capacity(inputPath string) (int, error) {
+	// Read audio file
+	carrier, err := readAudioFile(inputPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if e.Mode != "" && e.Mode != AudioModeLSB {
+		if !carrier.isWave() {
+			return 0, errors.New("container modes require a WAV carrier")
+		}
+		return UnlimitedCapacity, nil
+	}
+
+	// 1 bit per sample, minus the 32-bit length
+	capacity := (carrier.sampleCount() - 32) / 8
+	if e.Password != "" {
+		capacity -= EncryptionOverhead
+	}
+
+	return max(capacity, 0), nil
 }
 
-// DecodeData extracts hidden binary data from a WAV file
+// DecodeData extracts hidden binary data from an audio file
 func (e *AudioEncoder) DecodeData(inputPath string) ([]byte, error) {
-	// Read WAV file
-	_, audioData, err := readWavFile(inputPath)
+	return e.DecodeDataContext(context.Background(), inputPath, nil)
+}
+
+// DecodeDataContext is DecodeData with cancellation and progress reporting
+func (e *AudioEncoder) DecodeDataContext(ctx context.Context, inputPath string, progress Progress) ([]byte, error) {
+	t := newTracker(ctx, progress)
+
+	// Read audio file
+	carrier, err := readAudioFile(inputPath)
 	if err != nil {
 		return nil, err
 	}
+	if err := t.report(0.2); err != nil {
+		return nil, err
+	}
 
-	// Generate sample indices based on seed
-	indices := generateSampleOrder(len(audioData), 32, e.Seed) // Start with enough for length
+	// A payload stored by a container mode takes precedence over the samples
+	if data, ok := e.extractFromContainer(carrier); ok {
+		return data, nil
+	}
 
-	// Extract length first
-	var lengthBytes [4]byte
-	for i := 0; i < 32; i++ {
-		bit := audioData[indices[i]] & 1
-		byteIndex := i / 8
-		bitPosition := 7 - (i % 8)
-		lengthBytes[byteIndex] |= bit << bitPosition
+	totalSamples := carrier.sampleCount()
+	if totalSamples < 32 {
+		return nil, errors.New("audio file is too short to hold a message")
 	}
 
-	dataLength := binary.BigEndian.Uint32(lengthBytes[:])
-	if dataLength > uint32(len(audioData)/8) {
+	// Extract the payload from the LSB of each sample
+	extractedData, ok, err := e.extractLSBPayload(t, carrier.Samples, totalSamples, carrier.lsbOffset)
+	if err == nil && !ok && carrier.Format == "wav" && carrier.bytesPerSample() > 1 {
+		// Before AIFF and RF64 support, WAV payloads were hidden in the LSB of
+		// every data byte rather than of every sample; read those files too
+		extractedData, ok, err = e.extractLSBPayload(t, carrier.Samples, len(carrier.Samples), func(i int) int { return i })
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, errors.New("invalid data length")
 	}
 
-	// Generate indices for the full message
-	indices = generateSampleOrder(len(audioData), int(dataLength)*8+32, e.Seed)
-
-	// Extract data
-	extractedData := make([]byte, dataLength)
-	for i := 0; i < int(dataLength); i++ {
-		for b := 0; b < 8; b++ {
-			bitIndex := 32 + i*8 + b
-			bit := audioData[indices[bitIndex]] & 1
-			extractedData[i] |= bit << (7 - b)
+	// Decrypt the payload if a password is set
+	if e.Password != "" {
+		extractedData, err = DecryptData(extractedData, e.Password)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return extractedData, nil
+	return extractedData, t.report(1)
 }
 
 // EncodeMessage is a convenience method that encodes a text message
@@ -136,8 +200,57 @@ func (e *AudioEncoder) DecodeMessage(inputPath string) (string, error) {
 
 // Helper functions
 
+// extractLSBPayload reads a length-prefixed payload from the LSBs of count
+// positions in the seeded order; offset maps a position to its byte in samples.
+// It reports false when the length header does not fit in the positions.
+func (e *AudioEncoder) extractLSBPayload(t *tracker, samples []byte, count int, offset func(i int) int) ([]byte, bool, error) {
+	// Generate indices based on seed
+	indices, err := generateSampleOrder(t.span(0.2, 0.2), count, 32, e.Seed) // Start with enough for length
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Extract length first
+	var lengthBytes [4]byte
+	for i := 0; i < 32; i++ {
+		bit := samples[offset(indices[i])] & 1
+		byteIndex := i / 8
+		bitPosition := 7 - (i % 8)
+		lengthBytes[byteIndex] |= bit << bitPosition
+	}
+
+	dataLength := binary.BigEndian.Uint32(lengthBytes[:])
+	if dataLength > uint32((count-32)/8) {
+		return nil, false, nil
+	}
+
+	// Generate indices for the full message
+	indices, err = generateSampleOrder(t.span(0.2, 0.9), count, int(dataLength)*8+32, e.Seed)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Extract data
+	extractedData := make([]byte, dataLength)
+	if err := t.phase(0.9, 1, int(dataLength)); err != nil {
+		return nil, false, err
+	}
+	for i := 0; i < int(dataLength); i++ {
+		if err := t.advance(1); err != nil {
+			return nil, false, err
+		}
+		for b := 0; b < 8; b++ {
+			bitIndex := 32 + i*8 + b
+			bit := samples[offset(indices[bitIndex])] & 1
+			extractedData[i] |= bit << (7 - b)
+		}
+	}
+
+	return extractedData, true, nil
+}
+
 // generateSampleOrder creates a deterministic order of sample indices
-func generateSampleOrder(totalSamples, requiredBits int, seed int64) []int {
+func generateSampleOrder(t *tracker, totalSamples, requiredBits int, seed int64) ([]int, error) {
 	indices := make([]int, requiredBits)
 
 	if seed < 0 {
@@ -145,125 +258,27 @@ func generateSampleOrder(totalSamples, requiredBits int, seed int64) []int {
 		for i := 0; i < requiredBits; i++ {
 			indices[i] = i % totalSamples
 		}
-		return indices
+		return indices, nil
 	}
 
 	// Random mode with seed
 	rng := rand.New(rand.NewSource(seed))
 	used := make(map[int]bool)
 
+	if err := t.phase(0, 1, requiredBits); err != nil {
+		return nil, err
+	}
 	for i := 0; i < requiredBits; {
 		idx := rng.Intn(totalSamples)
 		if !used[idx] {
 			used[idx] = true
 			indices[i] = idx
 			i++
-		}
-	}
-
-	return indices
-}
-
-// readWavFile reads a WAV file and returns header and audio data
-// Improved readWavFile function
-func readWavFile(path string) ([]byte, []byte, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer f.Close()
-
-	// Read RIFF header
-	var riffID [4]byte
-	var fileSize uint32
-	var waveID [4]byte
-
-	if err := binary.Read(f, binary.LittleEndian, &riffID); err != nil {
-		return nil, nil, err
-	}
-	if string(riffID[:]) != "RIFF" {
-		return nil, nil, errors.New("not a valid WAV file")
-	}
-
-	if err := binary.Read(f, binary.LittleEndian, &fileSize); err != nil {
-		return nil, nil, err
-	}
-
-	if err := binary.Read(f, binary.LittleEndian, &waveID); err != nil {
-		return nil, nil, err
-	}
-	if string(waveID[:]) != "WAVE" {
-		return nil, nil, errors.New("not a valid WAV file")
-	}
-
-	// Find the data chunk
-	var dataFound bool
-	var dataSize uint32
-	var totalHeaderSize int64 = 12 // RIFF + size + WAVE
-
-	for !dataFound {
-		var chunkID [4]byte
-		var chunkSize uint32
-
-		if err := binary.Read(f, binary.LittleEndian, &chunkID); err != nil {
-			return nil, nil, err
-		}
-		if err := binary.Read(f, binary.LittleEndian, &chunkSize); err != nil {
-			return nil, nil, err
-		}
-
-		totalHeaderSize += 8 // chunk ID + chunk size
-
-		if string(chunkID[:]) == "data" {
-			dataFound = true
-			dataSize = chunkSize
-		} else {
-			// Skip this chunk
-			totalHeaderSize += int64(chunkSize)
-			if _, err := f.Seek(int64(chunkSize), io.SeekCurrent); err != nil {
-				return nil, nil, err
+			if err := t.advance(1); err != nil {
+				return nil, err
 			}
 		}
 	}
 
-	// Go back to beginning and read entire header
-	if _, err := f.Seek(0, io.SeekStart); err != nil {
-		return nil, nil, err
-	}
-
-	header := make([]byte, totalHeaderSize)
-	if _, err := io.ReadFull(f, header); err != nil {
-		return nil, nil, err
-	}
-
-	// Read audio data
-	audioData := make([]byte, dataSize)
-	if _, err := io.ReadFull(f, audioData); err != nil {
-		return nil, nil, err
-	}
-
-	return header, audioData, nil
-}
-
-// writeWavFile writes header and audio data to a WAV file
-func writeWavFile(path string, header []byte, audioData []byte) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	// Write header
-	_, err = f.Write(header)
-	if err != nil {
-		return err
-	}
-
-	// Write data
-	_, err = f.Write(audioData)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return indices, nil
 }
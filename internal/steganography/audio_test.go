@@ -0,0 +1,75 @@
+package steganography
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestWAV writes 16-bit stereo PCM sample bytes to a WAV file and returns its path
+func writeTestWAV(t *testing.T, dir, name string, samples []byte) string {
+	t.Helper()
+
+	header := []byte("RIFF....WAVEfmt ")
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, 1)       // PCM
+	header = binary.LittleEndian.AppendUint16(header, 2)       // Channels
+	header = binary.LittleEndian.AppendUint32(header, 44100)   // Sample rate
+	header = binary.LittleEndian.AppendUint32(header, 44100*4) // Byte rate
+	header = binary.LittleEndian.AppendUint16(header, 4)       // Block align
+	header = binary.LittleEndian.AppendUint16(header, 16)      // Bits per sample
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(samples)))
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(header)-8+len(samples)))
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, append(header, samples...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAudioDecodesByteIndexedPayload(t *testing.T) {
+	dir := t.TempDir()
+	samples := make([]byte, 40000)
+	rand.New(rand.NewSource(1)).Read(samples)
+	message := "written before per-sample LSBs"
+
+	for _, seed := range []string{"", "legacy"} {
+		encoder, _ := NewAudioEncoder(seed)
+
+		// Hide the message the way the encoder used to: one bit in the LSB of each
+		// data byte, in the seeded order over all bytes
+		legacy := append([]byte(nil), samples...)
+		fullData := binary.BigEndian.AppendUint32(nil, uint32(len(message)))
+		fullData = append(fullData, message...)
+		indices, err := generateSampleOrder(backgroundTracker(), len(legacy), len(fullData)*8, encoder.Seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, index := range indices {
+			bit := (fullData[i/8] >> (7 - i%8)) & 1
+			legacy[index] = legacy[index]&0xFE | bit
+		}
+
+		decoded, err := encoder.DecodeMessage(writeTestWAV(t, dir, "legacy.wav", legacy))
+		if err != nil {
+			t.Fatalf("seed %q: DecodeMessage: %v", seed, err)
+		}
+		if decoded != message {
+			t.Errorf("seed %q: decoded %q, want %q", seed, decoded, message)
+		}
+
+		// Current stego files still decode from the sample LSBs
+		cover := writeTestWAV(t, dir, "cover.wav", samples)
+		stego := filepath.Join(dir, "stego.wav")
+		if err := encoder.EncodeMessage(cover, stego, message); err != nil {
+			t.Fatalf("seed %q: EncodeMessage: %v", seed, err)
+		}
+		if decoded, err := encoder.DecodeMessage(stego); err != nil || decoded != message {
+			t.Errorf("seed %q: round trip = %q, %v", seed, decoded, err)
+		}
+	}
+}
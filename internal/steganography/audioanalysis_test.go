@@ -0,0 +1,45 @@
+package steganography
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestAnalyzeAudioChannelNoiseFloor(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noise := func(n int) []int {
+		samples := make([]int, n)
+		for i := range samples {
+			samples[i] = int(1000*math.Sin(float64(i)/20)) + rng.Intn(64) - 32
+		}
+		return samples
+	}
+
+	tests := []struct {
+		name     string
+		samples  []int
+		measured bool
+	}{
+		{"shorter than a frame", noise(spectrumFrameSize - 1), false},
+		{"digital silence", make([]int, 4*spectrumFrameSize), false},
+		{"noisy signal", noise(4 * spectrumFrameSize), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeAudioChannel(tt.samples, 16)
+			if measured := report.NoiseFloorDb != nil; measured != tt.measured {
+				t.Fatalf("noise floor measured = %v, want %v", measured, tt.measured)
+			}
+			if tt.measured {
+				return
+			}
+
+			// Without a noise floor the score rests on the other two tests alone
+			want := clampRate(0.82*report.SamplePairRate + 0.18*report.HistogramProbability)
+			if math.Abs(report.Score-want) > 1e-12 {
+				t.Errorf("score = %v, want %v", report.Score, want)
+			}
+		})
+	}
+}
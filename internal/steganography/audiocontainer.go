@@ -0,0 +1,193 @@
+// audiocontainer.go - Zero-distortion payload storage in RIFF metadata chunks
+package steganography
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// AudioMode selects where the audio encoder stores the payload
+type AudioMode string
+
+const (
+	AudioModeLSB     AudioMode = "lsb"     // Sample LSBs (default)
+	AudioModeInfo    AudioMode = "info"    // Comment field of a LIST/INFO chunk (needs a password)
+	AudioModePadding AudioMode = "padding" // JUNK padding chunk (needs a password)
+)
+
+// UnlimitedCapacity is reported as the capacity of modes that are not bounded by the carrier
+const UnlimitedCapacity = -1
+
+// ParseAudioMode converts a mode name to an AudioMode, defaulting to LSB
+func ParseAudioMode(mode string) (AudioMode, error) {
+	switch AudioMode(mode) {
+	case "", AudioModeLSB:
+		return AudioModeLSB, nil
+	case AudioModeInfo, AudioModePadding:
+		return AudioMode(mode), nil
+	}
+	return "", errors.New("unknown audio mode: " + mode)
+}
+
+// embedInContainer stores the encrypted payload in a metadata chunk of a WAV carrier
+// A metadata chunk is readable by anyone, so container modes require a password
+func (e *AudioEncoder) embedInContainer(carrier *audioCarrier, data []byte) error {
+	if !carrier.isWave() {
+		return errors.New("container modes require a WAV carrier")
+	}
+	if e.Password == "" {
+		return errors.New("container modes require a password")
+	}
+
+	encrypted, err := EncryptData(data, e.Password)
+	if err != nil {
+		return err
+	}
+
+	switch e.Mode {
+	case AudioModeInfo:
+		comment := []byte(base64.StdEncoding.EncodeToString(encrypted) + "\x00")
+
+		// Reuse an existing LIST/INFO chunk, replacing its comment
+		for i, chunk := range carrier.Chunks {
+			if chunk.ID == "LIST" && len(chunk.Data) >= 4 && string(chunk.Data[0:4]) == "INFO" {
+				carrier.Chunks[i].Data = setInfoField(chunk.Data, "ICMT", comment)
+				return nil
+			}
+		}
+
+		info := setInfoField([]byte("INFO"), "ICMT", comment)
+		carrier.insertChunkBeforeData(audioChunk{ID: "LIST", Data: info})
+	case AudioModePadding:
+		// Length-prefixed ciphertext, replacing any existing padding
+		junk := make([]byte, 4+len(encrypted))
+		binary.BigEndian.PutUint32(junk[0:4], uint32(len(encrypted)))
+		copy(junk[4:], encrypted)
+
+		for i, chunk := range carrier.Chunks {
+			if chunk.ID == "JUNK" {
+				carrier.Chunks[i].Data = junk
+				return nil
+			}
+		}
+		carrier.insertChunkBeforeData(audioChunk{ID: "JUNK", Data: junk})
+	default:
+		return errors.New("unknown audio mode: " + string(e.Mode))
+	}
+
+	return nil
+}
+
+// extractFromContainer looks for a payload in the metadata chunks of a carrier
+// It returns false if no password is set or no chunk holds a payload that decrypts with it
+func (e *AudioEncoder) extractFromContainer(carrier *audioCarrier) ([]byte, bool) {
+	if e.Password == "" {
+		return nil, false
+	}
+
+	for _, chunk := range carrier.Chunks {
+		var encrypted []byte
+
+		switch chunk.ID {
+		case "LIST":
+			if len(chunk.Data) < 4 || string(chunk.Data[0:4]) != "INFO" {
+				continue
+			}
+			comment, ok := infoField(chunk.Data, "ICMT")
+			if !ok {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(trimNul(comment))
+			if err != nil {
+				continue
+			}
+			encrypted = decoded
+		case "JUNK":
+			if len(chunk.Data) < 4 {
+				continue
+			}
+			length := binary.BigEndian.Uint32(chunk.Data[0:4])
+			if uint64(length) > uint64(len(chunk.Data)-4) {
+				continue
+			}
+			encrypted = chunk.Data[4 : 4+length]
+		default:
+			continue
+		}
+
+		if data, err := DecryptData(encrypted, e.Password); err == nil {
+			return data, true
+		}
+	}
+
+	return nil, false
+}
+
+// insertChunkBeforeData inserts a chunk just before the sample data chunk
+func (c *audioCarrier) insertChunkBeforeData(chunk audioChunk) {
+	for i, existing := range c.Chunks {
+		if existing.ID == "data" {
+			c.Chunks = append(c.Chunks[:i], append([]audioChunk{chunk}, c.Chunks[i:]...)...)
+			return
+		}
+	}
+	c.Chunks = append(c.Chunks, chunk)
+}
+
+// infoField returns the value of a subchunk in a LIST/INFO chunk body
+func infoField(list []byte, id string) ([]byte, bool) {
+	offset := 4 // Skip the INFO list type
+	for offset+8 <= len(list) {
+		fieldID := string(list[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(list[offset+4 : offset+8]))
+		if offset+8+size > len(list) {
+			break
+		}
+		if fieldID == id {
+			return list[offset+8 : offset+8+size], true
+		}
+		offset += 8 + size + size%2
+	}
+	return nil, false
+}
+
+// setInfoField returns a LIST/INFO chunk body with the subchunk set to value,
+// replacing an existing subchunk with the same ID
+func setInfoField(list []byte, id string, value []byte) []byte {
+	result := append([]byte(nil), list[:4]...)
+
+	offset := 4
+	for offset+8 <= len(list) {
+		fieldID := string(list[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(list[offset+4 : offset+8]))
+		end := offset + 8 + size + size%2
+		if end > len(list) {
+			end = len(list)
+		}
+		if fieldID != id {
+			result = append(result, list[offset:end]...)
+		}
+		offset = end
+	}
+
+	var header [8]byte
+	copy(header[0:4], id)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(value)))
+	result = append(result, header[:]...)
+	result = append(result, value...)
+	if len(value)%2 == 1 {
+		result = append(result, 0)
+	}
+
+	return result
+}
+
+// trimNul strips trailing NUL terminators from a RIFF text field
+func trimNul(value []byte) string {
+	end := len(value)
+	for end > 0 && value[end-1] == 0 {
+		end--
+	}
+	return string(value[:end])
+}
@@ -0,0 +1,159 @@
+// audiometrics.go - Distortion metrics comparing cover and stego audio
+package steganography
+
+import (
+	"errors"
+	"math"
+)
+
+// maxSNR is reported when the stego samples are identical to the cover
+const maxSNR = 200.0
+
+// Segmental SNR parameters
+const (
+	segmentDuration  = 0.02 // Segment length in seconds
+	minSegmentLength = 64   // Samples per segment for very low sample rates
+	segmentSNRFloor  = -10.0
+	segmentSNRCeil   = 35.0
+)
+
+// AudioChannelMetrics holds the distortion of one audio channel
+type AudioChannelMetrics struct {
+	Channel        int     `json:"channel"`
+	SNR            float64 `json:"snr"`            // Signal-to-noise ratio in dB, capped at 200 for identical samples
+	SegmentalSNR   float64 `json:"segmentalSnr"`   // Mean of per-segment SNRs in dB, each clamped to [-10, 35]
+	PeakDeviation  int     `json:"peakDeviation"`  // Largest absolute sample difference
+	ChangedSamples int     `json:"changedSamples"` // Samples that differ from the cover
+}
+
+// AudioMetrics holds the differences between a cover and a stego audio file
+type AudioMetrics struct {
+	Format        string                `json:"format"`
+	Channels      int                   `json:"channels"`
+	SampleRate    int                   `json:"sampleRate"`
+	BitsPerSample int                   `json:"bitsPerSample"`
+	Frames        int                   `json:"frames"`
+	Reports       []AudioChannelMetrics `json:"reports"`
+	SNR           float64               `json:"snr"`           // Lowest channel SNR
+	SegmentalSNR  float64               `json:"segmentalSnr"`  // Lowest channel segmental SNR
+	PeakDeviation int                   `json:"peakDeviation"` // Largest channel peak deviation
+}
+
+// CompareAudioFiles computes the distortion between a cover and a stego audio
+// file. Both are decoded with the same readers the encoder uses, so the files
+// must share format, channel layout, sample size and length.
+func CompareAudioFiles(coverPath, stegoPath string) (*AudioMetrics, error) {
+	cover, err := readAudioFile(coverPath)
+	if err != nil {
+		return nil, err
+	}
+	stego, err := readAudioFile(stegoPath)
+	if err != nil {
+		return nil, err
+	}
+	return compareAudio(cover, stego)
+}
+
+// compareAudio computes the distortion between two parsed audio carriers
+func compareAudio(cover, stego *audioCarrier) (*AudioMetrics, error) {
+	if cover.Float || stego.Float {
+		return nil, errors.New("audio metrics require integer PCM samples")
+	}
+	if cover.Channels < 1 {
+		return nil, errors.New("invalid channel count")
+	}
+	if cover.Channels != stego.Channels || cover.BitsPerSample != stego.BitsPerSample ||
+		cover.SampleRate != stego.SampleRate || len(cover.Samples) != len(stego.Samples) {
+		return nil, errors.New("audio files have different formats or lengths")
+	}
+
+	frames := cover.sampleCount() / cover.Channels
+	if frames == 0 {
+		return nil, errors.New("audio files are empty")
+	}
+
+	metrics := &AudioMetrics{
+		Format:        cover.Format,
+		Channels:      cover.Channels,
+		SampleRate:    cover.SampleRate,
+		BitsPerSample: cover.BitsPerSample,
+		Frames:        frames,
+		SNR:           maxSNR,
+		SegmentalSNR:  maxSNR,
+	}
+
+	// Segment length for segmental SNR
+	segmentLength := int(float64(cover.SampleRate) * segmentDuration)
+	if segmentLength < minSegmentLength {
+		segmentLength = minSegmentLength
+	}
+
+	for ch := 0; ch < cover.Channels; ch++ {
+		report := AudioChannelMetrics{Channel: ch}
+
+		signalEnergy, noiseEnergy := 0.0, 0.0
+		segmentSignal, segmentNoise := 0.0, 0.0
+		segmentSum, segments := 0.0, 0
+
+		for i := 0; i < frames; i++ {
+			index := i*cover.Channels + ch
+			original := cover.sampleValue(index)
+			difference := stego.sampleValue(index) - original
+
+			if difference != 0 {
+				report.ChangedSamples++
+				if difference < 0 {
+					difference = -difference
+				}
+				if difference > report.PeakDeviation {
+					report.PeakDeviation = difference
+				}
+			}
+
+			s := float64(original) * float64(original)
+			n := float64(difference) * float64(difference)
+			signalEnergy += s
+			noiseEnergy += n
+			segmentSignal += s
+			segmentNoise += n
+
+			// Close the segment; silent segments are skipped as their SNR is undefined
+			if (i+1)%segmentLength == 0 || i == frames-1 {
+				if segmentSignal > 0 {
+					snr := segmentSNRCeil
+					if segmentNoise > 0 {
+						snr = 10 * math.Log10(segmentSignal/segmentNoise)
+					}
+					segmentSum += math.Max(segmentSNRFloor, math.Min(segmentSNRCeil, snr))
+					segments++
+				}
+				segmentSignal, segmentNoise = 0, 0
+			}
+		}
+
+		report.SNR = snrDb(signalEnergy, noiseEnergy)
+		report.SegmentalSNR = segmentSNRCeil
+		if segments > 0 {
+			report.SegmentalSNR = segmentSum / float64(segments)
+		}
+
+		// The file is only as good as its worst channel
+		metrics.SNR = math.Min(metrics.SNR, report.SNR)
+		metrics.SegmentalSNR = math.Min(metrics.SegmentalSNR, report.SegmentalSNR)
+		metrics.PeakDeviation = max(metrics.PeakDeviation, report.PeakDeviation)
+		metrics.Reports = append(metrics.Reports, report)
+	}
+
+	return metrics, nil
+}
+
+// snrDb converts signal and noise energies to an SNR in dB, capped at maxSNR
+func snrDb(signal, noise float64) float64 {
+	if noise == 0 {
+		return maxSNR
+	}
+	if signal == 0 {
+		return -maxSNR
+	}
+	return math.Max(-maxSNR, math.Min(maxSNR, 10*math.Log10(signal/noise)))
+}
@@ -0,0 +1,426 @@
+// avi.go - RIFF/AVI and OpenDML structure parsing and validation
+package steganography
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// aviStream describes a stream from the AVI header list
+type aviStream struct {
+	Type        string // fccType from strh: "vids", "auds", ...
+	Width       int
+	Height      int // Negative for top-down DIBs
+	BitCount    int
+	Compression uint32 // biCompression from strf (0 = BI_RGB)
+	Index       []byte // OpenDML indx chunk payload, if present
+}
+
+// aviChunk locates a data chunk inside a movi list
+type aviChunk struct {
+	ID     string // e.g. "00db", "00dc", "01wb"
+	Offset int    // Offset of the chunk payload in the file
+	Size   int    // Payload size in bytes
+}
+
+// aviSegment locates a top-level RIFF chunk: "AVI " first, then OpenDML "AVIX" extensions
+type aviSegment struct {
+	Type   string
+	Offset int // Offset of the RIFF chunk header
+	End    int // End of the RIFF chunk data, clamped to the file size
+}
+
+// aviFile holds the parsed structure of an AVI file
+type aviFile struct {
+	Streams    []aviStream
+	Segments   []aviSegment
+	Chunks     []aviChunk // Data chunks of every movi list in file order
+	MoviOffset int        // Offset of the first movi list data (after the "movi" type)
+}
+
+// parseAVI parses the header list and the movi data chunks of an AVI file,
+// including the AVIX segments that OpenDML files use past the first gigabyte
+func parseAVI(fileData []byte) (*aviFile, error) {
+	// Validate AVI file
+	if len(fileData) < 12 || string(fileData[0:4]) != "RIFF" || string(fileData[8:12]) != "AVI " {
+		return nil, errors.New("not a valid AVI file")
+	}
+
+	avi := &aviFile{}
+
+	// Locate the RIFF segments
+	for offset := 0; offset+12 <= len(fileData) && string(fileData[offset:offset+4]) == "RIFF"; {
+		size := int(binary.LittleEndian.Uint32(fileData[offset+4 : offset+8]))
+		segment := aviSegment{
+			Type:   string(fileData[offset+8 : offset+12]),
+			Offset: offset,
+			End:    min(offset+8+size, len(fileData)),
+		}
+		if len(avi.Segments) > 0 && segment.Type != "AVIX" {
+			break // Not part of the AVI
+		}
+		avi.Segments = append(avi.Segments, segment)
+		offset = segment.End + size%2
+	}
+
+	// Parse the stream headers
+	first := avi.Segments[0]
+	hdrlOffset, hdrlLength, err := findListChunk(fileData, 12, first.End, "hdrl")
+	if err != nil {
+		return nil, err
+	}
+	err = walkRiffChunks(fileData, hdrlOffset, hdrlOffset+hdrlLength, func(id string, offset, size int) error {
+		if id == "LIST" && size >= 4 && string(fileData[offset:offset+4]) == "strl" {
+			avi.Streams = append(avi.Streams, parseStreamList(fileData[offset+4:offset+size]))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the data chunks of every movi list in every segment
+	avi.MoviOffset = -1
+	for _, segment := range avi.Segments {
+		for _, movi := range findListChunks(fileData, segment.Offset+12, segment.End, "movi") {
+			if avi.MoviOffset < 0 {
+				avi.MoviOffset = movi[0]
+			}
+			if err := avi.collectChunks(fileData, movi[0], movi[0]+movi[1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if avi.MoviOffset < 0 {
+		return nil, errors.New("movi chunk not found")
+	}
+
+	return avi, nil
+}
+
+// collectChunks adds the data chunks between start and end, descending into rec lists
+func (a *aviFile) collectChunks(fileData []byte, start, end int) error {
+	return walkRiffChunks(fileData, start, end, func(id string, offset, size int) error {
+		if id == "LIST" {
+			if size >= 4 && string(fileData[offset:offset+4]) == "rec " {
+				return a.collectChunks(fileData, offset+4, offset+size)
+			}
+			return nil
+		}
+		a.Chunks = append(a.Chunks, aviChunk{ID: id, Offset: offset, Size: size})
+		return nil
+	})
+}
+
+// parseStreamList reads the strh and strf chunks of a strl list
+func parseStreamList(data []byte) aviStream {
+	var stream aviStream
+
+	walkRiffChunks(data, 0, len(data), func(id string, offset, size int) error {
+		switch id {
+		case "strh":
+			if size >= 4 {
+				stream.Type = string(data[offset : offset+4])
+			}
+		case "strf":
+			// BITMAPINFOHEADER for video streams
+			if stream.Type == "vids" && size >= 20 {
+				stream.Width = int(int32(binary.LittleEndian.Uint32(data[offset+4 : offset+8])))
+				stream.Height = int(int32(binary.LittleEndian.Uint32(data[offset+8 : offset+12])))
+				stream.BitCount = int(binary.LittleEndian.Uint16(data[offset+14 : offset+16]))
+				stream.Compression = binary.LittleEndian.Uint32(data[offset+16 : offset+20])
+			}
+		case "indx":
+			// OpenDML super-index
+			stream.Index = data[offset : offset+size]
+		}
+		return nil
+	})
+
+	return stream
+}
+
+// streamIndex returns the stream number encoded in a chunk ID such as "01wb"
+func streamIndex(chunkID string) (int, bool) {
+	if len(chunkID) != 4 {
+		return 0, false
+	}
+	index, err := strconv.Atoi(chunkID[0:2])
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// isUncompressedFrame reports whether a chunk holds uncompressed video frame pixels
+// "##db" chunks are DIB frames; "##dc" chunks qualify only for BI_RGB video streams
+func (a *aviFile) isUncompressedFrame(chunk aviChunk) bool {
+	index, ok := streamIndex(chunk.ID)
+	if !ok || chunk.Size == 0 {
+		return false
+	}
+
+	switch chunk.ID[2:4] {
+	case "db":
+		return true
+	case "dc":
+		return index < len(a.Streams) && a.Streams[index].Type == "vids" && a.Streams[index].Compression == 0
+	}
+	return false
+}
+
+// frameChunks returns the chunks holding uncompressed video frames
+func (a *aviFile) frameChunks() []aviChunk {
+	var frames []aviChunk
+	for _, chunk := range a.Chunks {
+		if a.isUncompressedFrame(chunk) {
+			frames = append(frames, chunk)
+		}
+	}
+	return frames
+}
+
+// frameBytes maps a logical index over the payloads of a set of chunks to file offsets
+type frameBytes struct {
+	chunks []aviChunk
+	starts []int // Logical index of the first byte of each chunk
+	total  int
+}
+
+// newFrameBytes creates a byte mapping over the given chunks
+func newFrameBytes(chunks []aviChunk) *frameBytes {
+	fb := &frameBytes{chunks: chunks, starts: make([]int, len(chunks))}
+	for i, chunk := range chunks {
+		fb.starts[i] = fb.total
+		fb.total += chunk.Size
+	}
+	return fb
+}
+
+// offset returns the file offset of logical byte i
+func (fb *frameBytes) offset(i int) int {
+	// Find the last chunk starting at or before i
+	c := sort.Search(len(fb.starts), func(n int) bool { return fb.starts[n] > i }) - 1
+	return fb.chunks[c].Offset + i - fb.starts[c]
+}
+
+// walkRiffChunks calls fn for each chunk between start and end with the offset and
+// size of its payload, honouring the RIFF even-size padding rule
+func walkRiffChunks(data []byte, start, end int, fn func(id string, offset, size int) error) error {
+	if end > len(data) {
+		end = len(data)
+	}
+
+	offset := start
+	for offset+8 <= end {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+
+		if offset+8+size > end {
+			return errors.New("chunk " + strconv.Quote(id) + " exceeds its parent")
+		}
+		if err := fn(id, offset+8, size); err != nil {
+			return err
+		}
+
+		offset += 8 + size + size%2
+	}
+
+	return nil
+}
+
+// findListChunk finds a LIST chunk of the given type and returns the offset and
+// length of its data after the list type
+func findListChunk(data []byte, start, end int, listType string) (int, int, error) {
+	lists := findListChunks(data, start, end, listType)
+	if len(lists) == 0 {
+		return 0, 0, errors.New(listType + " list not found")
+	}
+	return lists[0][0], lists[0][1], nil
+}
+
+// findListChunks finds every LIST chunk of the given type between start and end and
+// returns the offset and length of their data after the list type. A list cut
+// short by the end of the file is clamped rather than dropped.
+func findListChunks(data []byte, start, end int, listType string) [][2]int {
+	if end > len(data) {
+		end = len(data)
+	}
+
+	var lists [][2]int
+	for offset := start; offset+12 <= end; {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+
+		if id == "LIST" && size >= 4 && string(data[offset+8:offset+12]) == listType {
+			lists = append(lists, [2]int{offset + 12, min(size-4, end-offset-12)})
+		}
+
+		offset += 8 + size + size%2
+	}
+
+	return lists
+}
+
+// validateAVI checks that an AVI file is structurally sound: the RIFF and list
+// sizes are consistent, every idx1 entry points at a matching chunk, and the
+// OpenDML super-indexes and standard indexes point at matching chunks
+func validateAVI(fileData []byte) error {
+	avi, err := parseAVI(fileData)
+	if err != nil {
+		return err
+	}
+	return avi.validate(fileData)
+}
+
+// validate runs the checks of validateAVI on an already parsed file
+func (avi *aviFile) validate(fileData []byte) error {
+	for _, segment := range avi.Segments {
+		if segment.Offset+8+int(binary.LittleEndian.Uint32(fileData[segment.Offset+4:segment.Offset+8])) > len(fileData) {
+			return errors.New("RIFF size exceeds the file size")
+		}
+	}
+
+	// Map chunk header offsets to chunks
+	chunkAt := make(map[int]aviChunk, len(avi.Chunks))
+	for _, chunk := range avi.Chunks {
+		chunkAt[chunk.Offset-8] = chunk
+	}
+
+	if err := validateIdx1(fileData, avi, chunkAt); err != nil {
+		return err
+	}
+
+	// Follow each stream's OpenDML index
+	for _, stream := range avi.Streams {
+		if len(stream.Index) == 0 {
+			continue
+		}
+		if err := validateODMLIndex(fileData, stream.Index, chunkAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateIdx1 checks the legacy index of the first RIFF segment
+func validateIdx1(fileData []byte, avi *aviFile, chunkAt map[int]aviChunk) error {
+	// Locate the legacy index
+	idxOffset, idxSize := -1, 0
+	err := walkRiffChunks(fileData, 12, avi.Segments[0].End, func(id string, offset, size int) error {
+		if id == "idx1" {
+			idxOffset, idxSize = offset, size
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if idxOffset < 0 {
+		return nil // The index is optional
+	}
+
+	// Index offsets are relative to the "movi" list type, or absolute in some writers
+	base := avi.MoviOffset - 4
+	if idxSize >= 16 {
+		first := int(binary.LittleEndian.Uint32(fileData[idxOffset+8 : idxOffset+12]))
+		if _, ok := chunkAt[base+first]; !ok {
+			if _, ok := chunkAt[first]; ok {
+				base = 0
+			}
+		}
+	}
+
+	for entry := idxOffset; entry+16 <= idxOffset+idxSize; entry += 16 {
+		id := string(fileData[entry : entry+4])
+		offset := int(binary.LittleEndian.Uint32(fileData[entry+8 : entry+12]))
+		size := int(binary.LittleEndian.Uint32(fileData[entry+12 : entry+16]))
+
+		if id == "rec " || id[0:2] == "ix" {
+			continue // Index entries for lists and index chunks are not data chunks
+		}
+		chunk, ok := chunkAt[base+offset]
+		if !ok || chunk.ID != id || chunk.Size != size {
+			return errors.New("idx1 entry for " + strconv.Quote(id) + " does not match the movi list")
+		}
+	}
+
+	return nil
+}
+
+// OpenDML index types
+const (
+	aviIndexOfIndexes = 0x00
+	aviIndexOfChunks  = 0x01
+)
+
+// validateODMLIndex checks an OpenDML indx chunk, which is either a super-index
+// pointing at ix## standard index chunks or a standard index itself
+func validateODMLIndex(fileData []byte, index []byte, chunkAt map[int]aviChunk) error {
+	if len(index) < 24 {
+		return errors.New("OpenDML index is too short")
+	}
+
+	longsPerEntry := int(binary.LittleEndian.Uint16(index[0:2]))
+	indexType := index[3]
+	entries := int(binary.LittleEndian.Uint32(index[4:8]))
+
+	switch indexType {
+	case aviIndexOfChunks:
+		return validateStandardIndex(index, chunkAt)
+	case aviIndexOfIndexes:
+		if longsPerEntry != 4 || 24+entries*16 > len(index) {
+			return errors.New("invalid OpenDML super-index")
+		}
+		for i := 0; i < entries; i++ {
+			entry := index[24+i*16:]
+			offset := binary.LittleEndian.Uint64(entry[0:8])
+			size := int(binary.LittleEndian.Uint32(entry[8:12]))
+
+			// Each entry points at the header of an ix## chunk
+			chunk, ok := chunkAt[int(offset)]
+			if offset > uint64(len(fileData)) || !ok || chunk.ID[0:2] != "ix" || chunk.Size+8 != size {
+				return errors.New("OpenDML super-index entry does not match an index chunk")
+			}
+			if err := validateStandardIndex(fileData[chunk.Offset:chunk.Offset+chunk.Size], chunkAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errors.New("unsupported OpenDML index type")
+}
+
+// validateStandardIndex checks that every entry of an OpenDML standard index
+// points at the payload of a matching data chunk
+func validateStandardIndex(index []byte, chunkAt map[int]aviChunk) error {
+	if len(index) < 24 {
+		return errors.New("OpenDML standard index is too short")
+	}
+
+	longsPerEntry := int(binary.LittleEndian.Uint16(index[0:2]))
+	entries := int(binary.LittleEndian.Uint32(index[4:8]))
+	id := string(index[8:12])
+	base := binary.LittleEndian.Uint64(index[12:20])
+
+	if longsPerEntry < 2 || 24+entries*longsPerEntry*4 > len(index) {
+		return errors.New("invalid OpenDML standard index")
+	}
+
+	for i := 0; i < entries; i++ {
+		entry := index[24+i*longsPerEntry*4:]
+		offset := base + uint64(binary.LittleEndian.Uint32(entry[0:4]))
+		size := int(binary.LittleEndian.Uint32(entry[4:8]) & 0x7FFFFFFF) // Top bit marks non-keyframes
+
+		// Entries point at the chunk payload, after its 8-byte header
+		chunk, ok := chunkAt[int(offset)-8]
+		if offset < 8 || !ok || chunk.ID != id || chunk.Size != size {
+			return errors.New("OpenDML index entry for " + strconv.Quote(id) + " does not match the movi list")
+		}
+	}
+
+	return nil
+}
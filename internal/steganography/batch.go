@@ -0,0 +1,142 @@
+// batch.go - Running an encoder over many carriers concurrently
+package steganography
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// BatchItem is one carrier of a batch
+// An empty method selects the default method for the carrier's extension
+type BatchItem struct {
+	Name       string // Name reported in the result, e.g. the path inside an archive
+	InputPath  string
+	OutputPath string // Stego output; unused when decoding
+	Method     Method
+	Data       []byte // Payload to hide; unused when decoding
+}
+
+// BatchResult is the outcome for one carrier of a batch
+type BatchResult struct {
+	Name        string `json:"name"`
+	Method      Method `json:"method"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+	PayloadSize int    `json:"payloadSize,omitempty"` // Bytes hidden or recovered, after decryption
+	Data        []byte `json:"-"`                     // Payload hidden or recovered
+}
+
+// BatchOptions are the settings shared by every item of a batch
+type BatchOptions struct {
+	Seed     string
+	Password string // Encrypts each payload with AES-GCM when set
+	Workers  int    // Concurrent items; defaults to the number of CPUs
+
+	// Context cancels the batch; items in flight stop and the rest fail with its error
+	Context context.Context
+}
+
+// BatchEncode hides each item's payload in its carrier, running up to
+// options.Workers items at once. Results are returned in item order, and a
+// failing item does not stop the others.
+func BatchEncode(items []BatchItem, options BatchOptions) []BatchResult {
+	return runBatch(items, options, func(ctx context.Context, item BatchItem, encoder Embedder) ([]byte, error) {
+		data := item.Data
+		if options.Password != "" {
+			var err error
+			data, err = EncryptData(data, options.Password)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return item.Data, encoder.EncodeDataContext(ctx, item.InputPath, item.OutputPath, data, nil)
+	})
+}
+
+// BatchDecode recovers the payload of each carrier, running up to
+// options.Workers items at once. Results are returned in item order.
+func BatchDecode(items []BatchItem, options BatchOptions) []BatchResult {
+	return runBatch(items, options, func(ctx context.Context, item BatchItem, encoder Embedder) ([]byte, error) {
+		data, err := encoder.DecodeDataContext(ctx, item.InputPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		if options.Password != "" {
+			return DecryptData(data, options.Password)
+		}
+		return data, nil
+	})
+}
+
+// Helper functions
+
+// batchProcess hides or recovers the payload of one item with its encoder
+type batchProcess func(ctx context.Context, item BatchItem, encoder Embedder) ([]byte, error)
+
+// runBatch runs process on every item with a pool of workers and collects the results
+func runBatch(items []BatchItem, options BatchOptions, process batchProcess) []BatchResult {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	results := make([]BatchResult, len(items))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(items)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = runBatchItem(ctx, items[i], options.Seed, process)
+			}
+		}()
+	}
+
+	for i := range items {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	return results
+}
+
+// runBatchItem resolves an item's method, creates its encoder and processes it
+func runBatchItem(ctx context.Context, item BatchItem, seed string, process batchProcess) BatchResult {
+	result := BatchResult{Name: item.Name, Method: item.Method}
+
+	if result.Method.Name == "" {
+		methods := CarrierMethods(item.InputPath)
+		if len(methods) == 0 {
+			result.Error = "unsupported carrier type " + filepath.Ext(item.InputPath)
+			return result
+		}
+		result.Method = methods[0]
+	}
+
+	// Skip the work once the batch is canceled
+	err := ctx.Err()
+	var encoder Embedder
+	if err == nil {
+		encoder, err = NewEncoder(seed, result.Method)
+	}
+	if err == nil {
+		result.Data, err = process(ctx, item, encoder)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Data = nil
+		return result
+	}
+
+	result.Success = true
+	result.PayloadSize = len(result.Data)
+	return result
+}
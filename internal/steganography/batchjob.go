@@ -0,0 +1,259 @@
+// batchjob.go - Batch manifests and jobs that pack payloads and report per carrier
+package steganography
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// batchMessageName is the file name a message payload is hidden under
+const batchMessageName = "message.txt"
+
+// BatchManifest assigns payloads and methods to the carriers of a batch
+// Carriers without an entry of their own, and entries that leave the payload or
+// method out, use the default payload and method
+type BatchManifest struct {
+	Method  Method              `json:"method"`  // Default method; empty selects by carrier extension
+	Message string              `json:"message"` // Default payload text
+	File    string              `json:"file"`    // Default payload file name
+	Items   []BatchManifestItem `json:"items"`
+}
+
+// BatchManifestItem sets the payload and method of one carrier
+type BatchManifestItem struct {
+	Carrier string `json:"carrier"` // Carrier name: its path in the archive or directory
+	Message string `json:"message"`
+	File    string `json:"file"`
+	Method  Method `json:"method"`
+}
+
+// BatchCarrier is a carrier file of a batch
+type BatchCarrier struct {
+	Name string // Path in the archive or directory, with forward slashes
+	Path string // Readable path on disk
+}
+
+// BatchReportItem is the report entry for one carrier
+type BatchReportItem struct {
+	BatchResult
+	Output   string `json:"output,omitempty"`   // Output name in the archive or directory
+	FileName string `json:"fileName,omitempty"` // Name of the hidden file
+	Message  string `json:"message,omitempty"`  // Text of a recovered message
+}
+
+// BatchReport is the per-item status report of a batch
+type BatchReport struct {
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Items     []BatchReportItem `json:"items"`
+}
+
+// BatchJob is a batch of carriers prepared for encoding or decoding
+type BatchJob struct {
+	items   []BatchItem
+	reports []BatchReportItem
+	errs    []error // Preparation errors; items that failed are not run
+}
+
+// ParseBatchManifest parses a JSON batch manifest
+func ParseBatchManifest(data []byte) (*BatchManifest, error) {
+	var manifest BatchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.New("invalid manifest: " + err.Error())
+	}
+	return &manifest, nil
+}
+
+// NewBatchEncodeJob prepares every carrier for encoding with the payload and method
+// the manifest gives it. payloadPath maps a payload file name from the manifest to
+// a readable path, and outputs are written under outputDir with the carrier's name.
+func NewBatchEncodeJob(manifest *BatchManifest, carriers []BatchCarrier, payloadPath func(name string) (string, bool), outputDir string) *BatchJob {
+	entries := manifest.entries()
+	outputs := make(map[string]string)
+	job := &BatchJob{}
+	for _, carrier := range carriers {
+		// An entry may override only the method and keep the default payload
+		entry := entries[carrier.Name]
+		if entry.Message == "" && entry.File == "" {
+			entry.Message, entry.File = manifest.Message, manifest.File
+		}
+		method := entry.Method
+		if method.Name == "" {
+			method = manifest.Method
+		}
+
+		// Image carriers are written as PNG; other carriers keep their format
+		outputName := carrier.Name
+		if methods := CarrierMethods(outputName); len(methods) == 0 || methods[0].Name == MethodLSB {
+			ext := filepath.Ext(outputName)
+			outputName = strings.TrimSuffix(outputName, ext) + ".png"
+		}
+
+		// Pack the payload with its file metadata
+		var payload []byte
+		var fileName string
+		var err error
+		switch {
+		case entry.File != "":
+			fileName = path.Base(entry.File)
+			if filePath, found := payloadPath(entry.File); found {
+				payload, err = PrepareFileData(filePath, fileName)
+			} else {
+				err = errors.New("payload file " + strconv.Quote(entry.File) + " not found")
+			}
+		case entry.Message != "":
+			fileName = batchMessageName
+			payload, err = PackFileData([]byte(entry.Message), fileName)
+		default:
+			err = errors.New("no payload for this carrier")
+		}
+		if other, taken := outputs[outputName]; taken && err == nil {
+			err = errors.New("output " + outputName + " would overwrite the output of " + other)
+		}
+		outputs[outputName] = carrier.Name
+
+		job.add(BatchItem{
+			Name:       carrier.Name,
+			InputPath:  carrier.Path,
+			OutputPath: filepath.Join(outputDir, filepath.FromSlash(outputName)),
+			Method:     method,
+			Data:       payload,
+		}, BatchReportItem{Output: outputName, FileName: fileName}, err)
+	}
+
+	return job
+}
+
+// NewBatchDecodeJob prepares every carrier for decoding with the method the
+// manifest gives it; the manifest may be nil
+func NewBatchDecodeJob(manifest *BatchManifest, carriers []BatchCarrier) *BatchJob {
+	if manifest == nil {
+		manifest = &BatchManifest{}
+	}
+
+	entries := manifest.entries()
+	job := &BatchJob{}
+	for _, carrier := range carriers {
+		method := entries[carrier.Name].Method
+		if method.Name == "" {
+			method = manifest.Method
+		}
+
+		job.add(BatchItem{
+			Name:      carrier.Name,
+			InputPath: carrier.Path,
+			Method:    method,
+		}, BatchReportItem{}, nil)
+	}
+
+	return job
+}
+
+// Encode hides the payloads and reports the status of every carrier
+func (j *BatchJob) Encode(options BatchOptions) *BatchReport {
+	// Create the output directories
+	for i, item := range j.items {
+		if j.errs[i] == nil {
+			j.errs[i] = os.MkdirAll(filepath.Dir(item.OutputPath), 0755)
+		}
+	}
+
+	return j.run(BatchEncode, options, func(report *BatchReportItem) error { return nil })
+}
+
+// Decode recovers the hidden files into outputDir, each under a directory named
+// after its carrier, and reports the status of every carrier
+func (j *BatchJob) Decode(options BatchOptions, outputDir string) *BatchReport {
+	return j.run(BatchDecode, options, func(report *BatchReportItem) error {
+		metadata, fileData, err := ExtractFileData(report.Data)
+		if err != nil {
+			return err
+		}
+
+		report.FileName = metadata.FileName
+		if metadata.FileName == batchMessageName && utf8.Valid(fileData) {
+			report.Message = string(fileData)
+		}
+
+		// Write the file under the carrier's full name, so carriers that differ only in
+		// their extension do not collide
+		report.Output = path.Join(report.Name, metadata.SafeFileName())
+		outputPath := filepath.Join(outputDir, filepath.FromSlash(report.Output))
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(outputPath, fileData, 0644)
+	})
+}
+
+// Helper functions
+
+// entries indexes the manifest items by carrier name
+func (m *BatchManifest) entries() map[string]BatchManifestItem {
+	entries := make(map[string]BatchManifestItem, len(m.Items))
+	for _, item := range m.Items {
+		entries[CleanBatchName(item.Carrier)] = item
+	}
+	return entries
+}
+
+// add appends a prepared item, its report entry and any preparation error
+func (j *BatchJob) add(item BatchItem, report BatchReportItem, err error) {
+	report.Name = item.Name
+	report.Method = item.Method
+	j.items = append(j.items, item)
+	j.reports = append(j.reports, report)
+	j.errs = append(j.errs, err)
+}
+
+// run processes the prepared items, finishes each successful one and builds the report
+func (j *BatchJob) run(process func([]BatchItem, BatchOptions) []BatchResult, options BatchOptions, finish func(report *BatchReportItem) error) *BatchReport {
+	// Run only the items that were prepared
+	var ready []BatchItem
+	for i, item := range j.items {
+		if j.errs[i] == nil {
+			ready = append(ready, item)
+		}
+	}
+	results := process(ready, options)
+
+	report := &BatchReport{Total: len(j.items)}
+	for i := range j.items {
+		item := j.reports[i]
+		if j.errs[i] != nil {
+			item.Error = j.errs[i].Error()
+		} else {
+			output, fileName := item.Output, item.FileName
+			item.BatchResult, results = results[0], results[1:]
+			item.Output, item.FileName = output, fileName
+			if item.Success {
+				if err := finish(&item); err != nil {
+					item.Success, item.Error = false, err.Error()
+				}
+			}
+		}
+
+		if item.Success {
+			report.Succeeded++
+		} else {
+			item.Output = ""
+			report.Failed++
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	return report
+}
+
+// CleanBatchName normalizes a path inside an archive or directory to a
+// relative path with forward slashes that cannot climb out of its root
+func CleanBatchName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+}
@@ -0,0 +1,39 @@
+package steganography
+
+import (
+	"testing"
+)
+
+func TestNewBatchEncodeJobDefaultPayload(t *testing.T) {
+	manifest, err := ParseBatchManifest([]byte(`{
+		"message": "default",
+		"items": [
+			{"carrier": "method.png", "method": {"name": "bpcs"}},
+			{"carrier": "own.png", "message": "own"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	carriers := []BatchCarrier{{Name: "method.png"}, {Name: "own.png"}, {Name: "plain.png"}}
+	noFiles := func(name string) (string, bool) { return "", false }
+	job := NewBatchEncodeJob(manifest, carriers, noFiles, t.TempDir())
+
+	want := map[string]string{"method.png": "default", "own.png": "own", "plain.png": "default"}
+	for i, item := range job.items {
+		if job.errs[i] != nil {
+			t.Errorf("%s: %v", item.Name, job.errs[i])
+			continue
+		}
+		_, message, err := ExtractFileData(item.Data)
+		if err != nil {
+			t.Fatalf("%s: %v", item.Name, err)
+		}
+		if string(message) != want[item.Name] {
+			t.Errorf("%s: payload = %q, want %q", item.Name, message, want[item.Name])
+		}
+	}
+	if method := job.items[0].Method.Name; method != MethodBPCS {
+		t.Errorf("method.png: method = %q, want %q", method, MethodBPCS)
+	}
+}
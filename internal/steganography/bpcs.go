@@ -1,6 +1,7 @@
 package steganography
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"image"
@@ -10,36 +11,23 @@ import (
 	mathrand "math/rand"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 )
 
 // BPCSEncoder handles BPCS steganography encoding
 type BPCSEncoder struct {
 	Seed                int64
-	ComplexityThreshold float64 // Threshold for determining complex regions (0.3-0.5 recommended)
+	ComplexityThreshold float64 // Threshold for determining complex regions, at least 0.3 and below 0.5
 }
 
 // NewBPCSEncoder creates a new BPCS encoder with the given seed
 func NewBPCSEncoder(seed string, complexityThreshold float64) (*BPCSEncoder, error) {
-	var seedInt int64 = -1 // Default to -1
-
-	if seed != "" {
-		// Convert seed string to int64
-		var err error
-		seedInt, err = strconv.ParseInt(seed, 10, 64)
-		if err != nil {
-			// If not a number, use string hash as seed
-			h := 0
-			for i := 0; i < len(seed); i++ {
-				h = 31*h + int(seed[i])
-			}
-			seedInt = int64(h)
-		}
-	}
+	seedInt := parseSeed(seed)
 
 	// Validate complexity threshold
-	if complexityThreshold < 0.3 || complexityThreshold > 0.5 {
+	// A conjugated block of complexity a has complexity 1-a, which is above the
+	// threshold for every simple block only when the threshold is below 0.5
+	if complexityThreshold < 0.3 || complexityThreshold >= 0.5 {
 		complexityThreshold = 0.45 // Default value if out of range
 	}
 
@@ -51,6 +39,13 @@ func NewBPCSEncoder(seed string, complexityThreshold float64) (*BPCSEncoder, err
 
 // EncodeData embeds binary data into an image using BPCS steganography
 func (e *BPCSEncoder) EncodeData(inputPath, outputPath string, data []byte) error {
+	return e.EncodeDataContext(context.Background(), inputPath, outputPath, data, nil)
+}
+
+// EncodeDataContext is EncodeData with cancellation and progress reporting
+func (e *BPCSEncoder) EncodeDataContext(ctx context.Context, inputPath, outputPath string, data []byte, progress Progress) error {
+	t := newTracker(ctx, progress)
+
 	// Check if input is JPG and convert if needed
 	ext := strings.ToLower(filepath.Ext(inputPath))
 	var img image.Image
@@ -82,35 +77,20 @@ func (e *BPCSEncoder) EncodeData(inputPath, outputPath string, data []byte) erro
 
 	// Create a new RGBA image to modify
 	rgbaImg := image.NewRGBA(bounds)
+	if err := t.phase(0, 0.1, width*height); err != nil {
+		return err
+	}
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
 			rgbaImg.Set(x, y, img.At(x, y))
 		}
-	}
-
-	// Get data length
-	dataLength := uint32(len(data))
-
-	// Create a byte slice for the length (4 bytes) + data
-	fullData := make([]byte, 4+dataLength)
-	binary.BigEndian.PutUint32(fullData[0:4], dataLength)
-	copy(fullData[4:], data)
-
-	// Convert data to bit planes
-	dataBlocks := convertDataToBlocks(fullData)
-
-	// Conjugate blocks to ensure complexity
-	for i := range dataBlocks {
-		if calculateComplexity(dataBlocks[i]) < e.ComplexityThreshold {
-			dataBlocks[i] = conjugateBlock(dataBlocks[i])
-			// Mark this block as conjugated (set a flag in the header)
-			// This would be implemented in a real system
+		if err := t.advance(width); err != nil {
+			return err
 		}
 	}
 
-	// Find complex regions in the image and embed data
-	err = e.embedDataInComplexRegions(rgbaImg, dataBlocks)
-	if err != nil {
+	// Embed the data
+	if err := e.embedInImage(t.span(0.1, 0.9), rgbaImg, data); err != nil {
 		return err
 	}
 
@@ -126,11 +106,21 @@ func (e *BPCSEncoder) EncodeData(inputPath, outputPath string, data []byte) erro
 		CompressionLevel: png.NoCompression,
 	}
 
-	return encoder.Encode(outFile, rgbaImg)
+	if err := encoder.Encode(outFile, rgbaImg); err != nil {
+		return err
+	}
+	return t.report(1)
 }
 
 // DecodeData extracts hidden binary data from an image
 func (e *BPCSEncoder) DecodeData(inputPath string) ([]byte, error) {
+	return e.DecodeDataContext(context.Background(), inputPath, nil)
+}
+
+// DecodeDataContext is DecodeData with cancellation and progress reporting
+func (e *BPCSEncoder) DecodeDataContext(ctx context.Context, inputPath string, progress Progress) ([]byte, error) {
+	t := newTracker(ctx, progress)
+
 	// Open the input image
 	file, err := os.Open(inputPath)
 	if err != nil {
@@ -144,17 +134,87 @@ func (e *BPCSEncoder) DecodeData(inputPath string) ([]byte, error) {
 		return nil, err
 	}
 
+	data, err := e.extractFromImage(t, img)
+	if err != nil {
+		return nil, err
+	}
+	return data, t.report(1)
+}
+
+// Capacity returns the largest payload in bytes that EncodeData can hide in the image
+// It scans the image for blocks above the complexity threshold
+func (e *BPCSEncoder) Capacity(inputPath string) (int, error) {
+	img, err := decodeImageFile(inputPath)
+	if err != nil {
+		return 0, err
+	}
+	return e.imageCapacity(toRGBA(img)), nil
+}
+
+// imageCapacity returns the largest payload in bytes that fits in the complex
+// blocks of an RGBA image, after the 4-byte length prefix
+func (e *BPCSEncoder) imageCapacity(rgbaImg *image.RGBA) int {
+	bounds := rgbaImg.Bounds()
+	width, height := bounds.Max.X, bounds.Max.Y
+
+	// Count the complex blocks in the planes used for embedding
+	// Embedding in one bit plane does not change the complexity of the others
+	complexBlocks := 0
+	for plane := 0; plane <= 5; plane++ {
+		for y := 0; y+8 <= height; y += 8 {
+			for x := 0; x+8 <= width; x += 8 {
+				for channel := 0; channel < 3; channel++ {
+					if calculateComplexity(extractBitPlaneBlock(rgbaImg, x, y, plane, channel)) > e.ComplexityThreshold {
+						complexBlocks++
+					}
+				}
+			}
+		}
+	}
+
+	return max(complexBlocks*blockDataBits/8-4, 0)
+}
+
+// embedInImage embeds binary data into the complex bit-plane blocks of an RGBA image
+func (e *BPCSEncoder) embedInImage(t *tracker, rgbaImg *image.RGBA, data []byte) error {
+	// Get data length
+	dataLength := uint32(len(data))
+
+	// Create a byte slice for the length (4 bytes) + data
+	fullData := make([]byte, 4+dataLength)
+	binary.BigEndian.PutUint32(fullData[0:4], dataLength)
+	copy(fullData[4:], data)
+
+	// Convert data to bit planes
+	dataBlocks := convertDataToBlocks(fullData)
+
+	// Conjugate blocks to ensure complexity
+	// The checkerboard sets the flag bit of a conjugated block
+	// A block that is still simple would be skipped by the decoder, so fail instead
+	for i := range dataBlocks {
+		if calculateComplexity(dataBlocks[i]) <= e.ComplexityThreshold {
+			dataBlocks[i] = conjugateBlock(dataBlocks[i])
+			if calculateComplexity(dataBlocks[i]) <= e.ComplexityThreshold {
+				return errors.New("complexity threshold must be below 0.5")
+			}
+		}
+	}
+
+	// Find complex regions in the image and embed data
+	return e.embedDataInComplexRegions(t, rgbaImg, dataBlocks)
+}
+
+// extractFromImage extracts hidden binary data from the complex bit-plane blocks of an image
+func (e *BPCSEncoder) extractFromImage(t *tracker, img image.Image) ([]byte, error) {
 	// Extract data blocks from complex regions
-	dataBlocks, err := e.extractDataFromComplexRegions(img)
+	dataBlocks, err := e.extractDataFromComplexRegions(t, img)
 	if err != nil {
 		return nil, err
 	}
 
-	// Deconjugate blocks if needed
+	// Deconjugate blocks that carry the conjugation flag
 	for i := range dataBlocks {
-		// Check if this block was conjugated (would check a flag in the header)
-		// For now, we'll check complexity and deconjugate if needed
-		if calculateComplexity(dataBlocks[i]) > 0.9 {
+		if dataBlocks[i][0][0] {
 			dataBlocks[i] = conjugateBlock(dataBlocks[i])
 		}
 	}
@@ -239,21 +299,21 @@ func conjugateBlock(block Block) Block {
 	return result
 }
 
+// blockDataBits is the number of payload bits per block
+// Bit [0][0] is reserved as the conjugation flag, so it is always clear before conjugation
+const blockDataBits = 63
+
 // convertDataToBlocks converts a byte array to bit blocks
 func convertDataToBlocks(data []byte) []Block {
 	// Calculate how many blocks we need
-	blockCount := (len(data)*8 + 63) / 64 // Each block holds 64 bits (8x8)
+	blockCount := (len(data)*8 + blockDataBits - 1) / blockDataBits
 	blocks := make([]Block, blockCount)
 
 	for byteIndex := 0; byteIndex < len(data); byteIndex++ {
 		for bitIndex := 0; bitIndex < 8; bitIndex++ {
 			// Calculate which block and position this bit belongs to
-			blockIndex := (byteIndex*8 + bitIndex) / 64
-			if blockIndex >= len(blocks) {
-				break
-			}
-
-			position := (byteIndex*8 + bitIndex) % 64
+			blockIndex := (byteIndex*8 + bitIndex) / blockDataBits
+			position := (byteIndex*8+bitIndex)%blockDataBits + 1 // Skip the flag bit
 			row := position / 8
 			col := position % 8
 
@@ -269,25 +329,23 @@ func convertDataToBlocks(data []byte) []Block {
 // convertBlocksToData converts bit blocks back to a byte array
 func convertBlocksToData(blocks []Block) []byte {
 	// Calculate how many bytes we need
-	byteCount := (len(blocks)*64 + 7) / 8 // Each block holds 64 bits
+	byteCount := len(blocks) * blockDataBits / 8
 	data := make([]byte, byteCount)
 
 	for blockIndex, block := range blocks {
-		for row := 0; row < 8; row++ {
-			for col := 0; col < 8; col++ {
-				// Calculate which byte and bit position this belongs to
-				bitPosition := blockIndex*64 + row*8 + col
-				byteIndex := bitPosition / 8
-				if byteIndex >= len(data) {
-					break
-				}
+		for position := 1; position < 64; position++ {
+			// Calculate which byte and bit position this belongs to
+			bitPosition := blockIndex*blockDataBits + position - 1
+			byteIndex := bitPosition / 8
+			if byteIndex >= len(data) {
+				break
+			}
 
-				bitIndex := 7 - (bitPosition % 8)
+			bitIndex := 7 - (bitPosition % 8)
 
-				// Set the bit in the byte
-				if block[row][col] {
-					data[byteIndex] |= 1 << bitIndex
-				}
+			// Set the bit in the byte
+			if block[position/8][position%8] {
+				data[byteIndex] |= 1 << bitIndex
 			}
 		}
 	}
@@ -296,7 +354,7 @@ func convertBlocksToData(blocks []Block) []byte {
 }
 
 // embedDataInComplexRegions embeds data blocks in complex regions of the image
-func (e *BPCSEncoder) embedDataInComplexRegions(img *image.RGBA, dataBlocks []Block) error {
+func (e *BPCSEncoder) embedDataInComplexRegions(t *tracker, img *image.RGBA, dataBlocks []Block) error {
 	bounds := img.Bounds()
 	width, height := bounds.Max.X, bounds.Max.Y
 
@@ -311,6 +369,11 @@ func (e *BPCSEncoder) embedDataInComplexRegions(img *image.RGBA, dataBlocks []Bl
 	// Track which data block we're currently embedding
 	currentDataBlock := 0
 
+	// Progress counts block visits over the six embedding planes
+	if err := t.phase(0, 1, 6*len(blockOrder)); err != nil {
+		return err
+	}
+
 	// For each bit plane (0-7) in each color channel (R,G,B)
 	for plane := 0; plane < 8; plane++ {
 		// Skip the most significant bit planes to preserve image quality
@@ -322,6 +385,9 @@ func (e *BPCSEncoder) embedDataInComplexRegions(img *image.RGBA, dataBlocks []Bl
 			if currentDataBlock >= len(dataBlocks) {
 				return nil // All data has been embedded
 			}
+			if err := t.advance(1); err != nil {
+				return err
+			}
 
 			// Extract the current image block for each channel
 			redBlock := extractBitPlaneBlock(img, blockPos.X, blockPos.Y, plane, 0)
@@ -369,7 +435,7 @@ func (e *BPCSEncoder) embedDataInComplexRegions(img *image.RGBA, dataBlocks []Bl
 }
 
 // extractDataFromComplexRegions extracts data blocks from complex regions
-func (e *BPCSEncoder) extractDataFromComplexRegions(img image.Image) ([]Block, error) {
+func (e *BPCSEncoder) extractDataFromComplexRegions(t *tracker, img image.Image) ([]Block, error) {
 	bounds := img.Bounds()
 	width, height := bounds.Max.X, bounds.Max.Y
 
@@ -386,10 +452,21 @@ func (e *BPCSEncoder) extractDataFromComplexRegions(img image.Image) ([]Block, e
 
 	// Create a temporary RGBA image for easier pixel manipulation
 	rgbaImg := image.NewRGBA(bounds)
+	if err := t.phase(0, 0.1, width*height); err != nil {
+		return nil, err
+	}
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
 			rgbaImg.Set(x, y, img.At(x, y))
 		}
+		if err := t.advance(width); err != nil {
+			return nil, err
+		}
+	}
+
+	// Progress counts block visits over the six embedding planes
+	if err := t.phase(0.1, 1, 6*len(blockOrder)); err != nil {
+		return nil, err
 	}
 
 	// For each bit plane (0-7) in each color channel (R,G,B)
@@ -400,6 +477,10 @@ func (e *BPCSEncoder) extractDataFromComplexRegions(img image.Image) ([]Block, e
 		}
 
 		for _, blockPos := range blockOrder {
+			if err := t.advance(1); err != nil {
+				return nil, err
+			}
+
 			// Extract the current image block for each channel
 			redBlock := extractBitPlaneBlock(rgbaImg, blockPos.X, blockPos.Y, plane, 0)
 			greenBlock := extractBitPlaneBlock(rgbaImg, blockPos.X, blockPos.Y, plane, 1)
@@ -429,7 +510,8 @@ func (e *BPCSEncoder) extractDataFromComplexRegions(img image.Image) ([]Block, e
 
 // BlockPosition represents the position of an 8x8 block in the image
 type BlockPosition struct {
-	X, Y int // Top-left corner of the block
+	X int `json:"x"` // Top-left corner of the block
+	Y int `json:"y"`
 }
 
 // generateBlockOrder creates a pseudo-random order of blocks based on the seed
@@ -0,0 +1,63 @@
+package steganography
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// halfComplexPayload returns a payload whose blocks, with the length prefix,
+// include one of complexity exactly 0.5
+func halfComplexPayload(t *testing.T) []byte {
+	t.Helper()
+
+	rng := rand.New(rand.NewSource(1))
+	for attempt := 0; attempt < 100; attempt++ {
+		payload := make([]byte, 200)
+		rng.Read(payload)
+
+		fullData := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+		for _, block := range convertDataToBlocks(append(fullData, payload...)) {
+			if calculateComplexity(block) == 0.5 {
+				return payload
+			}
+		}
+	}
+	t.Fatal("no payload with a block of complexity 0.5")
+	return nil
+}
+
+func TestBPCSThresholdOfOneHalf(t *testing.T) {
+	payload := halfComplexPayload(t)
+	dir := t.TempDir()
+	cover := writeNoisePNG(t, dir, 96, 96, 1)
+
+	// The constructor falls back to the default for 0.5, so the payload round-trips
+	encoder, err := NewBPCSEncoder("bpcs-test", 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoder.ComplexityThreshold >= 0.5 {
+		t.Fatalf("ComplexityThreshold = %v, want below 0.5", encoder.ComplexityThreshold)
+	}
+
+	stego := filepath.Join(dir, "stego.png")
+	if err := encoder.EncodeData(cover, stego, payload); err != nil {
+		t.Fatalf("EncodeData: %v", err)
+	}
+	decoded, err := encoder.DecodeData(stego)
+	if err != nil {
+		t.Fatalf("DecodeData: %v", err)
+	}
+	if !bytes.Equal(decoded, payload) {
+		t.Error("payload corrupted")
+	}
+
+	// An encoder set up with 0.5 directly fails instead of corrupting the payload
+	direct := &BPCSEncoder{Seed: encoder.Seed, ComplexityThreshold: 0.5}
+	if err := direct.EncodeData(cover, filepath.Join(dir, "direct.png"), payload); err == nil {
+		t.Error("EncodeData with threshold 0.5 succeeded")
+	}
+}
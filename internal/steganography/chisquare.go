@@ -0,0 +1,189 @@
+// chisquare.go - Westfeld-Pfitzmann chi-square attack on LSB embedding
+package steganography
+
+import (
+	"image"
+	"math"
+)
+
+// chiSquareMinWindow is the smallest window, in samples, the test is run on
+// Smaller windows do not hold enough samples per pair of values to be meaningful
+const chiSquareMinWindow = 1024
+
+// chiSquareWindows is the number of windows the scan is divided into for large images
+const chiSquareWindows = 64
+
+// ChiSquarePoint is the embedding probability of one window in scan order
+type ChiSquarePoint struct {
+	Position    int     `json:"position"` // Index of the first pixel of the window in scan order
+	Probability float64 `json:"probability"`
+}
+
+// ChiSquareChannel holds the chi-square test results for one colour channel
+type ChiSquareChannel struct {
+	Channel     string           `json:"channel"`
+	Probability float64          `json:"probability"` // Probability over the whole channel
+	Points      []ChiSquarePoint `json:"points"`
+}
+
+// ChiSquareResult holds the chi-square test results for an image
+type ChiSquareResult struct {
+	Width      int                `json:"width"`
+	Height     int                `json:"height"`
+	WindowSize int                `json:"windowSize"` // Window size in pixels
+	Step       int                `json:"step"`       // Distance between window starts in pixels
+	Channels   []ChiSquareChannel `json:"channels"`
+}
+
+// ChiSquareAnalyze runs the Westfeld-Pfitzmann pairs-of-values chi-square test on
+// each colour channel of an image. A sliding window moves along the scan order
+// (left to right, top to bottom), and each window gets the probability that its
+// least significant bits carry embedded data. Values near 1 mean the histogram
+// pairs (2k, 2k+1) are almost equal, which LSB replacement causes.
+func ChiSquareAnalyze(img image.Image) *ChiSquareResult {
+	width, height, samples := channelSamples(img)
+	total := width * height
+
+	// Choose the window size and step
+	windowSize := max(total/chiSquareWindows, chiSquareMinWindow)
+	if windowSize > total {
+		windowSize = total
+	}
+	step := max(windowSize/2, 1)
+
+	result := &ChiSquareResult{
+		Width:      width,
+		Height:     height,
+		WindowSize: windowSize,
+		Step:       step,
+	}
+
+	for c, name := range channelNames {
+		channel := ChiSquareChannel{
+			Channel:     name,
+			Probability: chiSquareProbability(samples[c]),
+		}
+		for start := 0; start+windowSize <= total && windowSize > 0; start += step {
+			channel.Points = append(channel.Points, ChiSquarePoint{
+				Position:    start,
+				Probability: chiSquareProbability(samples[c][start : start+windowSize]),
+			})
+		}
+		result.Channels = append(result.Channels, channel)
+	}
+
+	return result
+}
+
+// Helper functions
+
+// channelNames names the colour channels returned by channelSamples
+var channelNames = []string{"red", "green", "blue"}
+
+// channelSamples returns the image size and the 8-bit red, green and blue samples
+// of an image in scan order
+func channelSamples(img image.Image) (int, int, [3][]uint8) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	total := width * height
+
+	samples := [3][]uint8{make([]uint8, total), make([]uint8, total), make([]uint8, total)}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			i := y*width + x
+			samples[0][i] = uint8(r >> 8)
+			samples[1][i] = uint8(g >> 8)
+			samples[2][i] = uint8(b >> 8)
+		}
+	}
+
+	return width, height, samples
+}
+
+// chiSquareProbability returns the probability that a set of samples carries
+// LSB-embedded data, from the chi-square statistic over the pairs of values
+func chiSquareProbability(samples []uint8) float64 {
+	var histogram [256]int
+	for _, v := range samples {
+		histogram[v]++
+	}
+
+	return chiSquarePairs(histogram[:])
+}
+
+// chiSquarePairs computes the pairs-of-values chi-square probability from a
+// histogram whose even indices hold even sample values
+func chiSquarePairs(histogram []int) float64 {
+	chi := 0.0
+	categories := 0
+	for k := 0; 2*k+1 < len(histogram); k++ {
+		even, odd := histogram[2*k], histogram[2*k+1]
+
+		// Skip pairs with too few samples for the expected count to be reliable
+		if even+odd <= 4 {
+			continue
+		}
+
+		expected := float64(even+odd) / 2
+		diff := float64(even) - expected
+		chi += diff * diff / expected
+		categories++
+	}
+
+	if categories < 2 {
+		return 0
+	}
+
+	// Probability of a statistic at least this large with categories-1 degrees of freedom
+	return gammaQ(float64(categories-1)/2, chi/2)
+}
+
+// gammaQ computes the regularized upper incomplete gamma function Q(a, x)
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+
+	lgammaA, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgammaA)
+
+	if x < a+1 {
+		// Series expansion of P(a, x)
+		sum, term := 1/a, 1/a
+		for n := 1; n < 500; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return math.Max(0, 1-sum*prefix)
+	}
+
+	// Continued fraction for Q(a, x) using the modified Lentz method
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < 500; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Min(1, prefix*h)
+}
@@ -0,0 +1,292 @@
+// deniable.go - Deniable dual-payload LSB steganography
+package steganography
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"strconv"
+)
+
+// DeniableEncoder hides one or two payloads under their own passwords in the LSBs
+// of one image. The seed's pixel order is split into two halves of channel slots;
+// each payload is encrypted and written to a randomly chosen half in an order
+// derived from its password, and the rest of both halves, including a half without
+// a payload, is filled with random bits. An image with one payload is built exactly
+// like one with two, so a password only reveals its own payload and nothing shows
+// whether a second one exists.
+//
+// Deniability covers the number of payloads, not their presence: every RGB LSB is
+// rewritten, so LSB steganalysis such as ChiSquareAnalyze and EstimateEmbeddingRate
+// reports the output as fully embedded.
+type DeniableEncoder struct {
+	Seed int64
+}
+
+// NewDeniableEncoder creates a new dual-payload encoder with the given seed
+func NewDeniableEncoder(seed string) (*DeniableEncoder, error) {
+	seedInt := parseSeed(seed)
+
+	return &DeniableEncoder{
+		Seed: seedInt,
+	}, nil
+}
+
+// Encode hides a single payload under a password
+// The other half is filled with random bits, so the output cannot be told apart
+// from that of EncodeDual.
+func (e *DeniableEncoder) Encode(inputPath, outputPath string, data []byte, password string) error {
+	return e.EncodeContext(context.Background(), inputPath, outputPath, data, password, nil)
+}
+
+// EncodeContext is Encode with cancellation and progress reporting
+func (e *DeniableEncoder) EncodeContext(ctx context.Context, inputPath, outputPath string, data []byte, password string, progress Progress) error {
+	if password == "" {
+		return errors.New("a password is required")
+	}
+	return e.encode(newTracker(ctx, progress), inputPath, outputPath, [2][]byte{data}, [2]string{password})
+}
+
+// EncodeDual hides a decoy and a real payload in an image, each under its own password
+func (e *DeniableEncoder) EncodeDual(inputPath, outputPath string, decoy []byte, decoyPassword string, real []byte, realPassword string) error {
+	return e.EncodeDualContext(context.Background(), inputPath, outputPath, decoy, decoyPassword, real, realPassword, nil)
+}
+
+// EncodeDualContext is EncodeDual with cancellation and progress reporting
+func (e *DeniableEncoder) EncodeDualContext(ctx context.Context, inputPath, outputPath string, decoy []byte, decoyPassword string, real []byte, realPassword string, progress Progress) error {
+	if decoyPassword == "" || realPassword == "" {
+		return errors.New("both payloads need a password")
+	}
+	if decoyPassword == realPassword {
+		return errors.New("the decoy and real passwords must differ")
+	}
+	return e.encode(newTracker(ctx, progress), inputPath, outputPath, [2][]byte{decoy, real}, [2]string{decoyPassword, realPassword})
+}
+
+// encode writes each payload with a password to one half and fills a half
+// without a password with random bits
+func (e *DeniableEncoder) encode(t *tracker, inputPath, outputPath string, payloads [2][]byte, passwords [2]string) error {
+	img, err := decodeImageFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	// Work on non-premultiplied pixels, which PNG stores unchanged, so the LSBs
+	// of translucent pixels survive
+	nrgbaImg := toNRGBA(img)
+
+	// Choose which half holds which payload
+	var choice [1]byte
+	if _, err := rand.Read(choice[:]); err != nil {
+		return err
+	}
+	halves, err := e.slotHalves(t.span(0, 0.3), nrgbaImg)
+	if err != nil {
+		return err
+	}
+	if choice[0]&1 == 1 {
+		payloads[0], payloads[1] = payloads[1], payloads[0]
+		passwords[0], passwords[1] = passwords[1], passwords[0]
+	}
+
+	for h, half := range halves {
+		span := t.span(0.3+0.3*float64(h), 0.6+0.3*float64(h))
+		if passwords[h] == "" {
+			// No payload: random bits, which is what a sealed half looks like
+			noise := make([]byte, len(half)/8)
+			if _, err := rand.Read(noise); err != nil {
+				return err
+			}
+			if err := span.phase(0, 1, len(half)); err != nil {
+				return err
+			}
+			for i, offset := range half {
+				bit := (noise[i/8] >> (7 - i%8)) & 1
+				nrgbaImg.Pix[offset] = (nrgbaImg.Pix[offset] & 0xFE) | bit
+				if err := span.advance(1); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		stream, err := sealHalf(payloads[h], passwords[h], len(half)/8)
+		if err != nil {
+			return err
+		}
+
+		// Write the stream in the password's order
+		order, err := e.passwordOrder(span.span(0, 0.6), passwords[h], len(half))
+		if err != nil {
+			return err
+		}
+		if err := span.phase(0.6, 1, len(order)); err != nil {
+			return err
+		}
+		for i, slot := range order {
+			bit := (stream[i/8] >> (7 - i%8)) & 1
+			offset := half[slot]
+			nrgbaImg.Pix[offset] = (nrgbaImg.Pix[offset] & 0xFE) | bit
+			if err := span.advance(1); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Save the output image
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	// Use no compression for PNG to minimize file size changes
+	encoder := &png.Encoder{
+		CompressionLevel: png.NoCompression,
+	}
+
+	if err := encoder.Encode(outFile, nrgbaImg); err != nil {
+		return err
+	}
+	return t.report(1)
+}
+
+// Decode extracts the payload hidden under a password
+func (e *DeniableEncoder) Decode(inputPath, password string) ([]byte, error) {
+	return e.DecodeContext(context.Background(), inputPath, password, nil)
+}
+
+// DecodeContext is Decode with cancellation and progress reporting
+func (e *DeniableEncoder) DecodeContext(ctx context.Context, inputPath, password string, progress Progress) ([]byte, error) {
+	t := newTracker(ctx, progress)
+	if password == "" {
+		return nil, errors.New("a password is required")
+	}
+
+	img, err := decodeImageFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	nrgbaImg := toNRGBA(img)
+
+	// Only the half written under this password authenticates
+	halves, err := e.slotHalves(t.span(0, 0.4), nrgbaImg)
+	if err != nil {
+		return nil, err
+	}
+	for h, half := range halves {
+		span := t.span(0.4+0.3*float64(h), 0.7+0.3*float64(h))
+		order, err := e.passwordOrder(span.span(0, 0.8), password, len(half))
+		if err != nil {
+			return nil, err
+		}
+		stream := make([]byte, len(half)/8)
+		if err := span.phase(0.8, 1, len(half)); err != nil {
+			return nil, err
+		}
+		for i := range stream {
+			for b := 0; b < 8; b++ {
+				bit := nrgbaImg.Pix[half[order[i*8+b]]] & 1
+				stream[i] |= bit << (7 - b)
+			}
+			if err := span.advance(8); err != nil {
+				return nil, err
+			}
+		}
+
+		if data, err := openHalf(stream, password); err == nil {
+			return data, t.report(1)
+		}
+	}
+
+	return nil, errors.New("no payload found for this password")
+}
+
+// Capacity returns the largest payload in bytes that each password can hide
+func (e *DeniableEncoder) Capacity(inputPath string) (int, error) {
+	img, err := decodeImageFile(inputPath)
+	if err != nil {
+		return 0, err
+	}
+	bounds := img.Bounds()
+
+	// Half of the RGB slots, minus the length and encryption overhead
+	halfBytes := bounds.Dx() * bounds.Dy() * 3 / 2 / 8
+	return max(halfBytes-4-EncryptionOverhead, 0), nil
+}
+
+// Helper functions
+
+// slotHalves splits the seed's pixel order into two halves of R, G and B slots,
+// given as offsets into the image's Pix array
+func (e *DeniableEncoder) slotHalves(t *tracker, img *image.NRGBA) ([2][]int, error) {
+	bounds := img.Bounds()
+	rng := NewSeededRNG(e.Seed)
+	pixels, err := generatePixelOrder(t, bounds.Dx(), bounds.Dy(), rng)
+	if err != nil {
+		return [2][]int{}, err
+	}
+
+	slots := make([]int, 0, len(pixels)*3)
+	for _, pixel := range pixels {
+		offset := img.PixOffset(pixel.X, pixel.Y)
+		slots = append(slots, offset, offset+1, offset+2)
+	}
+
+	// Whole bytes per half
+	halfSize := len(slots) / 2 / 8 * 8
+	return [2][]int{slots[:halfSize], slots[halfSize : 2*halfSize]}, nil
+}
+
+// passwordOrder returns a permutation of a half's slots derived from the seed and password
+func (e *DeniableEncoder) passwordOrder(t *tracker, password string, n int) ([]int, error) {
+	key := sha256.Sum256([]byte(strconv.FormatInt(e.Seed, 10) + ":" + password))
+	rng := NewSeededRNG(int64(binary.BigEndian.Uint64(key[:8])))
+	return generatePermutation(t, n, rng)
+}
+
+// lengthMask hides the ciphertext length, so a half without the password is all noise
+func lengthMask(password string) uint32 {
+	key := sha256.Sum256([]byte("length:" + password))
+	return binary.BigEndian.Uint32(key[:4])
+}
+
+// sealHalf encrypts a payload into a stream of size bytes:
+// [4 bytes masked ciphertext length][ciphertext][random padding]
+func sealHalf(data []byte, password string, size int) ([]byte, error) {
+	ciphertext, err := EncryptData(data, password)
+	if err != nil {
+		return nil, err
+	}
+	if 4+len(ciphertext) > size {
+		return nil, errors.New("payload too large for half of the image")
+	}
+
+	stream := make([]byte, size)
+	if _, err := rand.Read(stream); err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint32(stream[0:4], uint32(len(ciphertext))^lengthMask(password))
+	copy(stream[4:], ciphertext)
+
+	return stream, nil
+}
+
+// openHalf decrypts the payload from a stream written by sealHalf
+func openHalf(stream []byte, password string) ([]byte, error) {
+	if len(stream) < 4 {
+		return nil, errors.New("stream too short")
+	}
+
+	length := binary.BigEndian.Uint32(stream[0:4]) ^ lengthMask(password)
+	if length > uint32(len(stream)-4) {
+		return nil, errors.New("invalid data length")
+	}
+
+	return DecryptData(stream[4:4+length], password)
+}
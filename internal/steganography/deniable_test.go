@@ -0,0 +1,55 @@
+package steganography
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeniableTranslucentCover(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.png")
+	file, err := os.Create(cover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = png.Encode(file, randomNRGBA(64, 64, []uint8{0, 1, 64, 128, 200, 0xFF}, 3))
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoder, err := NewDeniableEncoder("deniable-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both payloads decode under their own password
+	dual := filepath.Join(dir, "dual.png")
+	if err := encoder.EncodeDual(cover, dual, []byte("decoy"), "decoy-pw", []byte("real"), "real-pw"); err != nil {
+		t.Fatalf("EncodeDual: %v", err)
+	}
+	for password, want := range map[string]string{"decoy-pw": "decoy", "real-pw": "real"} {
+		data, err := encoder.Decode(dual, password)
+		if err != nil {
+			t.Fatalf("Decode(%s): %v", password, err)
+		}
+		if !bytes.Equal(data, []byte(want)) {
+			t.Errorf("Decode(%s) = %q, want %q", password, data, want)
+		}
+	}
+	if _, err := encoder.Decode(dual, "other-pw"); err == nil {
+		t.Error("another password decoded a payload")
+	}
+
+	// A single payload decodes too
+	single := filepath.Join(dir, "single.png")
+	if err := encoder.Encode(cover, single, []byte("only"), "pw"); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if data, err := encoder.Decode(single, "pw"); err != nil || string(data) != "only" {
+		t.Errorf("Decode = %q, %v, want \"only\"", data, err)
+	}
+}
@@ -0,0 +1,29 @@
+package steganography
+
+import (
+	"context"
+	"image"
+)
+
+// Embedder is implemented by every encoder that hides binary data in a carrier file
+// The Context variants stop with the context's error once it is canceled and report
+// their progress to an optional callback.
+type Embedder interface {
+	EncodeData(inputPath, outputPath string, data []byte) error
+	DecodeData(inputPath string) ([]byte, error)
+	EncodeDataContext(ctx context.Context, inputPath, outputPath string, data []byte, progress Progress) error
+	DecodeDataContext(ctx context.Context, inputPath string, progress Progress) ([]byte, error)
+}
+
+// CapacityEstimator is implemented by encoders that can report the largest payload
+// a carrier file holds with their current settings
+type CapacityEstimator interface {
+	Capacity(inputPath string) (int, error)
+}
+
+// imageEmbedder is implemented by the image encoders that can work on decoded images
+type imageEmbedder interface {
+	embedInImage(t *tracker, img *image.RGBA, data []byte) error
+	extractFromImage(t *tracker, img image.Image) ([]byte, error)
+	imageCapacity(img *image.RGBA) int
+}
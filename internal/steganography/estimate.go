@@ -0,0 +1,226 @@
+// estimate.go - Quantitative LSB steganalysis: RS analysis and Sample Pair Analysis
+package steganography
+
+import (
+	"image"
+	"math"
+)
+
+// spaRootTolerance is how far outside [0, 0.5] an SPA root may fall through noise alone
+const spaRootTolerance = 0.05
+
+// rsMask is the flipping mask applied to groups of four horizontally adjacent pixels
+var rsMask = [4]int{0, 1, 1, 0}
+
+// ChannelEstimate holds the estimated LSB embedding rate of one colour channel
+// Rates are the fraction of samples carrying message bits, from 0 to 1
+type ChannelEstimate struct {
+	Channel string  `json:"channel"`
+	RS      float64 `json:"rs"`  // Regular/Singular groups estimate
+	SPA     float64 `json:"spa"` // Sample Pair Analysis estimate
+}
+
+// EmbeddingEstimate holds the estimated LSB embedding rate of an image
+type EmbeddingEstimate struct {
+	Width    int               `json:"width"`
+	Height   int               `json:"height"`
+	Channels []ChannelEstimate `json:"channels"`
+	Rate     float64           `json:"rate"`     // Mean of the RS and SPA estimates over all channels
+	Capacity int               `json:"capacity"` // 1-bit LSB capacity of the image in bytes
+	Length   int               `json:"length"`   // Estimated message length in bytes
+}
+
+// EstimateEmbeddingRate estimates the fraction of pixels carrying LSB-embedded
+// data in each colour channel using RS analysis (Fridrich, Goljan and Du) and
+// Sample Pair Analysis (Dumitrescu, Wu and Wang). The estimated message length
+// assumes one bit per channel sample, as LSBEncoder embeds.
+func EstimateEmbeddingRate(img image.Image) *EmbeddingEstimate {
+	width, height, samples := channelSamples(img)
+
+	estimate := &EmbeddingEstimate{
+		Width:    width,
+		Height:   height,
+		Capacity: width * height * 3 / 8,
+	}
+
+	sum := 0.0
+	for c, name := range channelNames {
+		channel := ChannelEstimate{
+			Channel: name,
+			RS:      rsEstimate(samples[c], width, height),
+			SPA:     spaEstimate(samples[c], width, height),
+		}
+		sum += channel.RS + channel.SPA
+		estimate.Channels = append(estimate.Channels, channel)
+	}
+
+	estimate.Rate = sum / float64(2*len(channelNames))
+	estimate.Length = int(estimate.Rate * float64(estimate.Capacity))
+	return estimate
+}
+
+// Helper functions
+
+// rsEstimate estimates the embedding rate of a channel using RS analysis
+func rsEstimate(samples []uint8, width, height int) float64 {
+	// Group counts for the image as given and with every LSB flipped
+	d0, dn0 := rsCounts(samples, width, height, false)
+	d1, dn1 := rsCounts(samples, width, height, true)
+
+	// Solve 2(d1 + d0)z^2 + (d-0 - d-1 - d1 - 3d0)z + d0 - d-0 = 0
+	a := 2 * (d1 + d0)
+	b := dn0 - dn1 - d1 - 3*d0
+	c := d0 - dn0
+
+	var z float64
+	if math.Abs(a) < 1e-12 {
+		if math.Abs(b) < 1e-12 {
+			return 0
+		}
+		z = -c / b
+	} else {
+		// Covers have d0 close to d-0, so the roots are only complex near full embedding
+		discriminant := b*b - 4*a*c
+		if discriminant < 0 {
+			return 1
+		}
+		// Use the root with the smaller absolute value
+		z1 := (-b + math.Sqrt(discriminant)) / (2 * a)
+		z2 := (-b - math.Sqrt(discriminant)) / (2 * a)
+		z = z1
+		if math.Abs(z2) < math.Abs(z1) {
+			z = z2
+		}
+	}
+
+	if z == 0.5 {
+		return 1
+	}
+	return clampRate(z / (z - 0.5))
+}
+
+// rsCounts classifies groups of four horizontally adjacent samples and returns
+// R_M - S_M and R_-M - S_-M as fractions of the group count
+func rsCounts(samples []uint8, width, height int, flipped bool) (float64, float64) {
+	var regular, singular, regularNeg, singularNeg, groups int
+
+	var group, positive, negative [4]int
+	for y := 0; y < height; y++ {
+		row := samples[y*width : (y+1)*width]
+		for x := 0; x+4 <= width; x += 4 {
+			for i := 0; i < 4; i++ {
+				v := int(row[x+i])
+				if flipped {
+					v ^= 1
+				}
+				group[i] = v
+
+				// F1 flips 2k <-> 2k+1, F-1 flips 2k-1 <-> 2k
+				positive[i], negative[i] = v, v
+				if rsMask[i] == 1 {
+					positive[i] = v ^ 1
+					negative[i] = ((v + 1) ^ 1) - 1
+				}
+			}
+
+			f := groupSmoothness(group)
+			switch fp := groupSmoothness(positive); {
+			case fp > f:
+				regular++
+			case fp < f:
+				singular++
+			}
+			switch fn := groupSmoothness(negative); {
+			case fn > f:
+				regularNeg++
+			case fn < f:
+				singularNeg++
+			}
+			groups++
+		}
+	}
+
+	if groups == 0 {
+		return 0, 0
+	}
+	return float64(regular-singular) / float64(groups), float64(regularNeg-singularNeg) / float64(groups)
+}
+
+// groupSmoothness is the RS discrimination function: the sum of absolute
+// differences between neighbouring samples of a group
+func groupSmoothness(group [4]int) int {
+	sum := 0
+	for i := 0; i < 3; i++ {
+		d := group[i+1] - group[i]
+		if d < 0 {
+			d = -d
+		}
+		sum += d
+	}
+	return sum
+}
+
+// spaEstimate estimates the embedding rate of a channel using Sample Pair Analysis
+// on horizontally adjacent pairs
+func spaEstimate(samples []uint8, width, height int) float64 {
+	var x, y, k, pairs int
+
+	for row := 0; row < height; row++ {
+		line := samples[row*width : (row+1)*width]
+		for i := 0; i+1 < width; i++ {
+			u, v := int(line[i]), int(line[i+1])
+
+			if (v%2 == 0 && u < v) || (v%2 == 1 && u > v) {
+				x++
+			}
+			if (v%2 == 0 && u > v) || (v%2 == 1 && u < v) {
+				y++
+			}
+			if u/2 == v/2 {
+				k++
+			}
+			pairs++
+		}
+	}
+
+	return spaFromCounts(x, y, k, pairs)
+}
+
+// spaFromCounts computes the SPA embedding rate estimate from the pair counts:
+// x and y are the pairs whose ordering agrees and disagrees with the parity of the
+// second sample, and k the pairs whose samples differ only in the LSB
+func spaFromCounts(x, y, k, pairs int) float64 {
+	if k == 0 {
+		return 0
+	}
+
+	// The estimate is twice the smaller root of 2k*b^2 + 2(2x - n)*b + (y - x) = 0
+	a := 2 * float64(k)
+	b := 2 * float64(2*x-pairs)
+	c := float64(y - x)
+
+	// Covers have x close to y, so the roots are only complex near full embedding
+	discriminant := b*b - 4*a*c
+	if discriminant < 0 {
+		return 1
+	}
+	root1 := (-b + math.Sqrt(discriminant)) / (2 * a)
+	root2 := (-b - math.Sqrt(discriminant)) / (2 * a)
+
+	// Use the smaller root unless it lies well outside the valid range [0, 0.5]
+	// and the other root lies inside it
+	beta := math.Min(root1, root2)
+	if other := math.Max(root1, root2); beta < -spaRootTolerance && other <= 0.5+spaRootTolerance {
+		beta = other
+	}
+
+	return clampRate(2 * beta)
+}
+
+// clampRate limits an estimated embedding rate to [0, 1]
+func clampRate(rate float64) float64 {
+	if math.IsNaN(rate) {
+		return 0
+	}
+	return math.Max(0, math.Min(1, rate))
+}
@@ -0,0 +1,51 @@
+package steganography
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFileDataRoundTrip(t *testing.T) {
+	packed, err := PackFileData([]byte("contents"), "report.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, data, err := ExtractFileData(packed)
+	if err != nil {
+		t.Fatalf("ExtractFileData: %v", err)
+	}
+	if metadata.FileName != "report.pdf" || metadata.FileExt != ".pdf" || metadata.FileSize != 8 {
+		t.Errorf("metadata = %+v", metadata)
+	}
+	if !bytes.Equal(data, []byte("contents")) {
+		t.Errorf("data = %q", data)
+	}
+
+	if _, _, err := ExtractFileData(packed[:len(packed)-1]); err == nil {
+		t.Error("truncated payload accepted")
+	}
+}
+
+func TestSafeFileName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"notes.txt", "notes.txt"},
+		{"dir/notes.txt", "notes.txt"},
+		{"../../etc/passwd", "passwd"},
+		{`..\..\windows\win.ini`, "win.ini"},
+		{"", defaultFileName},
+		{".", defaultFileName},
+		{"..", defaultFileName},
+		{"/", defaultFileName},
+		{"a/..", defaultFileName},
+		{`..\`, defaultFileName},
+		{"dir/", "dir"},
+	}
+	for _, tt := range tests {
+		if got := (FileMetadata{FileName: tt.name}).SafeFileName(); got != tt.want {
+			t.Errorf("SafeFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// fragile.go - Fragile block watermark for tamper detection and localization
+package steganography
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"strconv"
+)
+
+// fragileBlockSize is the side of the authenticated blocks, the same 8x8 grid BPCS uses
+const fragileBlockSize = 8
+
+// fragileAuthBits is the number of authentication bits per block: one LSB in each
+// of the R, G and B channels of its 64 pixels
+const fragileAuthBits = fragileBlockSize * fragileBlockSize * 3
+
+// FragileEncoder writes a keyed hash of every 8x8 block into the block's own LSBs.
+// The hash covers the block's pixels above the LSBs, its position in the seed's
+// block order and the image size, so any edit to a block, moving it, or resizing
+// the image breaks the blocks involved while the rest still verify.
+type FragileEncoder struct {
+	Seed int64
+}
+
+// FragileReport is the outcome of verifying a fragile watermark
+type FragileReport struct {
+	Width          int             `json:"width"`
+	Height         int             `json:"height"`
+	BlocksX        int             `json:"blocksX"`
+	BlocksY        int             `json:"blocksY"`
+	TotalBlocks    int             `json:"totalBlocks"`
+	TamperedBlocks int             `json:"tamperedBlocks"`
+	Authentic      bool            `json:"authentic"` // No block was tampered with
+	Tampered       []BlockPosition `json:"tampered"`  // Top-left corners of tampered blocks
+	Map            []string        `json:"map"`       // One row per block row: '.' intact, 'X' tampered
+}
+
+// NewFragileEncoder creates a new fragile watermark encoder with the given seed
+func NewFragileEncoder(seed string) (*FragileEncoder, error) {
+	seedInt := parseSeed(seed)
+
+	return &FragileEncoder{
+		Seed: seedInt,
+	}, nil
+}
+
+// Embed writes an image with every block authenticated
+func (e *FragileEncoder) Embed(inputPath, outputPath string) error {
+	return e.EmbedContext(context.Background(), inputPath, outputPath, nil)
+}
+
+// EmbedContext is Embed with cancellation and progress reporting
+func (e *FragileEncoder) EmbedContext(ctx context.Context, inputPath, outputPath string, progress Progress) error {
+	img, err := decodeImageFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	marked, err := e.EmbedImageContext(ctx, img, progress)
+	if err != nil {
+		return err
+	}
+
+	// Save the output image
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	return png.Encode(outFile, marked)
+}
+
+// Verify checks every block of an image file
+func (e *FragileEncoder) Verify(inputPath string) (*FragileReport, error) {
+	return e.VerifyContext(context.Background(), inputPath, nil)
+}
+
+// VerifyContext is Verify with cancellation and progress reporting
+func (e *FragileEncoder) VerifyContext(ctx context.Context, inputPath string, progress Progress) (*FragileReport, error) {
+	img, err := decodeImageFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	return e.VerifyImageContext(ctx, img, progress)
+}
+
+// EmbedImage returns a copy of an image with every block authenticated
+// Pixels right of or below the last whole block are left unprotected. The output
+// is non-premultiplied, like PNG, so translucent pixels keep the exact colour
+// values the MACs are computed over.
+func (e *FragileEncoder) EmbedImage(img image.Image) (*image.NRGBA, error) {
+	return e.EmbedImageContext(context.Background(), img, nil)
+}
+
+// EmbedImageContext is EmbedImage with cancellation and progress reporting
+func (e *FragileEncoder) EmbedImageContext(ctx context.Context, img image.Image, progress Progress) (*image.NRGBA, error) {
+	t := newTracker(ctx, progress)
+	output := toNRGBA(img)
+	bounds := output.Bounds()
+	if bounds.Dx() < fragileBlockSize || bounds.Dy() < fragileBlockSize {
+		return nil, errors.New("image is too small for a fragile watermark")
+	}
+
+	order := e.blockOrder(bounds.Dx(), bounds.Dy())
+	if err := t.phase(0, 1, len(order)*fragileBlockSize*fragileBlockSize); err != nil {
+		return nil, err
+	}
+	for index, blockPos := range order {
+		mac := e.blockMAC(output, blockPos, index)
+
+		// Write the MAC bits into the block's LSBs
+		bit := 0
+		for y := 0; y < fragileBlockSize; y++ {
+			for x := 0; x < fragileBlockSize; x++ {
+				offset := output.PixOffset(bounds.Min.X+blockPos.X+x, bounds.Min.Y+blockPos.Y+y)
+				for c := 0; c < 3; c++ {
+					value := (mac[bit/8] >> (7 - bit%8)) & 1
+					output.Pix[offset+c] = (output.Pix[offset+c] & 0xFE) | value
+					bit++
+				}
+			}
+		}
+		if err := t.advance(fragileBlockSize * fragileBlockSize); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, t.report(1)
+}
+
+// VerifyImage checks every block of an image and maps the ones that fail
+func (e *FragileEncoder) VerifyImage(img image.Image) (*FragileReport, error) {
+	return e.VerifyImageContext(context.Background(), img, nil)
+}
+
+// VerifyImageContext is VerifyImage with cancellation and progress reporting
+func (e *FragileEncoder) VerifyImageContext(ctx context.Context, img image.Image, progress Progress) (*FragileReport, error) {
+	t := newTracker(ctx, progress)
+	nrgbaImg := toNRGBA(img)
+	bounds := nrgbaImg.Bounds()
+	if bounds.Dx() < fragileBlockSize || bounds.Dy() < fragileBlockSize {
+		return nil, errors.New("image is too small for a fragile watermark")
+	}
+
+	report := &FragileReport{
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		BlocksX:  bounds.Dx() / fragileBlockSize,
+		BlocksY:  bounds.Dy() / fragileBlockSize,
+		Tampered: []BlockPosition{},
+	}
+	report.TotalBlocks = report.BlocksX * report.BlocksY
+
+	rows := make([][]byte, report.BlocksY)
+	for y := range rows {
+		rows[y] = make([]byte, report.BlocksX)
+		for x := range rows[y] {
+			rows[y][x] = '.'
+		}
+	}
+
+	order := e.blockOrder(bounds.Dx(), bounds.Dy())
+	if err := t.phase(0, 1, len(order)*fragileBlockSize*fragileBlockSize); err != nil {
+		return nil, err
+	}
+	for index, blockPos := range order {
+		mac := e.blockMAC(nrgbaImg, blockPos, index)
+
+		// Read the stored bits from the block's LSBs
+		stored := make([]byte, fragileAuthBits/8)
+		bit := 0
+		for y := 0; y < fragileBlockSize; y++ {
+			for x := 0; x < fragileBlockSize; x++ {
+				offset := nrgbaImg.PixOffset(bounds.Min.X+blockPos.X+x, bounds.Min.Y+blockPos.Y+y)
+				for c := 0; c < 3; c++ {
+					stored[bit/8] |= (nrgbaImg.Pix[offset+c] & 1) << (7 - bit%8)
+					bit++
+				}
+			}
+		}
+
+		if !hmac.Equal(stored, mac) {
+			rows[blockPos.Y/fragileBlockSize][blockPos.X/fragileBlockSize] = 'X'
+		}
+		if err := t.advance(fragileBlockSize * fragileBlockSize); err != nil {
+			return nil, err
+		}
+	}
+
+	// Collect the tampered blocks in raster order
+	for y, row := range rows {
+		for x, cell := range row {
+			if cell == 'X' {
+				report.Tampered = append(report.Tampered, BlockPosition{X: x * fragileBlockSize, Y: y * fragileBlockSize})
+			}
+		}
+		report.Map = append(report.Map, string(row))
+	}
+	report.TamperedBlocks = len(report.Tampered)
+	report.Authentic = report.TamperedBlocks == 0
+
+	return report, t.report(1)
+}
+
+// TamperMapImage renders an image with its tampered blocks tinted red
+func TamperMapImage(img image.Image, report *FragileReport) image.Image {
+	output := image.NewRGBA(img.Bounds())
+	copy(output.Pix, toRGBA(img).Pix)
+	bounds := output.Bounds()
+
+	for _, blockPos := range report.Tampered {
+		for y := 0; y < fragileBlockSize; y++ {
+			for x := 0; x < fragileBlockSize; x++ {
+				offset := output.PixOffset(bounds.Min.X+blockPos.X+x, bounds.Min.Y+blockPos.Y+y)
+				output.Pix[offset] = uint8((int(output.Pix[offset]) + 255) / 2)
+				output.Pix[offset+1] /= 2
+				output.Pix[offset+2] /= 2
+				output.Pix[offset+3] = 0xFF
+			}
+		}
+	}
+
+	return output
+}
+
+// Helper functions
+
+// toNRGBA copies an image into a non-premultiplied NRGBA image whose bounds start
+// at the origin. Decoded PNGs with alpha are NRGBA already and copy exactly.
+func toNRGBA(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	nrgbaImg := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			nrgbaImg.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return nrgbaImg
+}
+
+// blockOrder returns the seed's order of whole 8x8 blocks
+func (e *FragileEncoder) blockOrder(width, height int) []BlockPosition {
+	rng := NewSeededRNG(e.Seed)
+	return generateBlockOrder(width/fragileBlockSize, height/fragileBlockSize, rng)
+}
+
+// blockMAC computes the authentication bits for a block from its pixels with the
+// R, G and B LSBs cleared, its alpha, its index in the block order and the image size
+func (e *FragileEncoder) blockMAC(img *image.NRGBA, blockPos BlockPosition, index int) []byte {
+	key := sha256.Sum256([]byte("fragile:" + strconv.FormatInt(e.Seed, 10)))
+	mac := hmac.New(sha256.New, key[:])
+
+	var header [16]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(img.Bounds().Dx()))
+	binary.BigEndian.PutUint32(header[4:8], uint32(img.Bounds().Dy()))
+	binary.BigEndian.PutUint32(header[8:12], uint32(index))
+	binary.BigEndian.PutUint16(header[12:14], uint16(blockPos.X/fragileBlockSize))
+	binary.BigEndian.PutUint16(header[14:16], uint16(blockPos.Y/fragileBlockSize))
+	mac.Write(header[:])
+
+	bounds := img.Bounds()
+	row := make([]byte, fragileBlockSize*4)
+	for y := 0; y < fragileBlockSize; y++ {
+		offset := img.PixOffset(bounds.Min.X+blockPos.X, bounds.Min.Y+blockPos.Y+y)
+		copy(row, img.Pix[offset:offset+len(row)])
+		for i := range row {
+			if i%4 != 3 {
+				row[i] &= 0xFE
+			}
+		}
+		mac.Write(row)
+	}
+
+	return mac.Sum(nil)[:fragileAuthBits/8]
+}
@@ -0,0 +1,109 @@
+package steganography
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+// pngRoundTrip encodes an image as PNG and decodes it again
+func pngRoundTrip(t *testing.T, img image.Image) image.Image {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// randomNRGBA returns a random image whose alpha is drawn from alphas
+func randomNRGBA(width, height int, alphas []uint8, seed int64) *image.NRGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{
+				uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)),
+				alphas[rng.Intn(len(alphas))],
+			})
+		}
+	}
+	return img
+}
+
+func TestFragileRoundTrip(t *testing.T) {
+	encoder, err := NewFragileEncoder("fragile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		alphas []uint8
+	}{
+		{"opaque", []uint8{0xFF}},
+		{"translucent", []uint8{0, 1, 64, 128, 200, 0xFF}},
+		{"transparent", []uint8{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marked, err := encoder.EmbedImage(randomNRGBA(64, 64, tt.alphas, 1))
+			if err != nil {
+				t.Fatalf("EmbedImage: %v", err)
+			}
+
+			report, err := encoder.VerifyImage(pngRoundTrip(t, marked))
+			if err != nil {
+				t.Fatalf("VerifyImage: %v", err)
+			}
+			if !report.Authentic || report.TamperedBlocks != 0 || report.TotalBlocks != 64 {
+				t.Errorf("unedited image: %d of %d blocks tampered", report.TamperedBlocks, report.TotalBlocks)
+			}
+		})
+	}
+}
+
+func TestFragileLocalizesTampering(t *testing.T) {
+	encoder, err := NewFragileEncoder("fragile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	marked, err := encoder.EmbedImage(randomNRGBA(64, 48, []uint8{90, 0xFF}, 2))
+	if err != nil {
+		t.Fatalf("EmbedImage: %v", err)
+	}
+
+	// Change one pixel above its LSB in the block at (24, 16)
+	edited := pngRoundTrip(t, marked).(*image.NRGBA)
+	offset := edited.PixOffset(27, 19)
+	edited.Pix[offset+1] ^= 0x10
+
+	report, err := encoder.VerifyImage(edited)
+	if err != nil {
+		t.Fatalf("VerifyImage: %v", err)
+	}
+	if report.Authentic || report.TamperedBlocks != 1 {
+		t.Fatalf("got %d tampered blocks, want 1", report.TamperedBlocks)
+	}
+	if report.Tampered[0] != (BlockPosition{X: 24, Y: 16}) {
+		t.Errorf("tampered block at %+v, want {24 16}", report.Tampered[0])
+	}
+
+	// Another seed sees every block as tampered
+	other, _ := NewFragileEncoder("other-seed")
+	report, err = other.VerifyImage(marked)
+	if err != nil {
+		t.Fatalf("VerifyImage: %v", err)
+	}
+	if report.TamperedBlocks != report.TotalBlocks {
+		t.Errorf("another seed verified %d blocks", report.TotalBlocks-report.TamperedBlocks)
+	}
+}
@@ -2,6 +2,7 @@
 package steganography
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"image"
@@ -11,7 +12,6 @@ import (
 	mathrand "math/rand"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 )
 
@@ -22,21 +22,7 @@ type LSBEncoder struct {
 
 // NewLSBEncoder creates a new LSB encoder with the given seed
 func NewLSBEncoder(seed string) (*LSBEncoder, error) {
-	var seedInt int64 = -1 // Default to -1
-
-	if seed != "" {
-		// Convert seed string to int64
-		var err error
-		seedInt, err = strconv.ParseInt(seed, 10, 64)
-		if err != nil {
-			// If not a number, use string hash as seed
-			h := 0
-			for i := 0; i < len(seed); i++ {
-				h = 31*h + int(seed[i])
-			}
-			seedInt = int64(h)
-		}
-	}
+	seedInt := parseSeed(seed)
 
 	return &LSBEncoder{
 		Seed: seedInt,
@@ -45,6 +31,13 @@ func NewLSBEncoder(seed string) (*LSBEncoder, error) {
 
 // EncodeData embeds binary data into an image using LSB steganography
 func (e *LSBEncoder) EncodeData(inputPath, outputPath string, data []byte) error {
+	return e.EncodeDataContext(context.Background(), inputPath, outputPath, data, nil)
+}
+
+// EncodeDataContext is EncodeData with cancellation and progress reporting
+func (e *LSBEncoder) EncodeDataContext(ctx context.Context, inputPath, outputPath string, data []byte, progress Progress) error {
+	t := newTracker(ctx, progress)
+
 	// Check if input is JPG and convert if needed
 	ext := strings.ToLower(filepath.Ext(inputPath))
 	var img image.Image
@@ -76,12 +69,96 @@ func (e *LSBEncoder) EncodeData(inputPath, outputPath string, data []byte) error
 
 	// Create a new RGBA image to modify
 	rgbaImg := image.NewRGBA(bounds)
+	if err := t.phase(0, 0.1, width*height); err != nil {
+		return err
+	}
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
 			rgbaImg.Set(x, y, img.At(x, y))
 		}
+		if err := t.advance(width); err != nil {
+			return err
+		}
+	}
+
+	// Embed the data
+	if err := e.embedInImage(t.span(0.1, 0.9), rgbaImg, data); err != nil {
+		return err
 	}
 
+	// Save the output image
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	// Use no compression for PNG to minimize file size changes
+	encoder := &png.Encoder{
+		CompressionLevel: png.NoCompression,
+	}
+
+	// If the original was a JPEG, we need to use PNG for lossless storage
+	// but we'll use minimal settings to keep file size down
+	if err := encoder.Encode(outFile, rgbaImg); err != nil {
+		return err
+	}
+	return t.report(1)
+}
+
+// DecodeData extracts hidden binary data from an image
+func (e *LSBEncoder) DecodeData(inputPath string) ([]byte, error) {
+	return e.DecodeDataContext(context.Background(), inputPath, nil)
+}
+
+// DecodeDataContext is DecodeData with cancellation and progress reporting
+func (e *LSBEncoder) DecodeDataContext(ctx context.Context, inputPath string, progress Progress) ([]byte, error) {
+	t := newTracker(ctx, progress)
+
+	// Open the input image
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Decode the image
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := e.extractFromImage(t, img)
+	if err != nil {
+		return nil, err
+	}
+	return data, t.report(1)
+}
+
+// Capacity returns the largest payload in bytes that EncodeData can hide in the image
+func (e *LSBEncoder) Capacity(inputPath string) (int, error) {
+	img, err := decodeImageFile(inputPath)
+	if err != nil {
+		return 0, err
+	}
+	return e.imageCapacity(toRGBA(img)), nil
+}
+
+// imageCapacity returns the largest payload in bytes that fits in an RGBA image
+func (e *LSBEncoder) imageCapacity(rgbaImg *image.RGBA) int {
+	bounds := rgbaImg.Bounds()
+	width, height := bounds.Max.X, bounds.Max.Y
+
+	// Same limit as embedInImage
+	return max((width*height*3)/8-8, 0)
+}
+
+// embedInImage embeds binary data into the LSBs of an RGBA image
+func (e *LSBEncoder) embedInImage(t *tracker, rgbaImg *image.RGBA, data []byte) error {
+	// Get image bounds
+	bounds := rgbaImg.Bounds()
+	width, height := bounds.Max.X, bounds.Max.Y
+
 	// Get data length
 	dataLength := uint32(len(data))
 
@@ -98,9 +175,15 @@ func (e *LSBEncoder) EncodeData(inputPath, outputPath string, data []byte) error
 
 	// Use seed to determine pixel order
 	rng := NewSeededRNG(e.Seed)
-	pixels := generatePixelOrder(width, height, rng)
+	pixels, err := generatePixelOrder(t.span(0, 0.6), width, height, rng)
+	if err != nil {
+		return err
+	}
 
 	// Embed the data
+	if err := t.phase(0.6, 1, len(fullData)*8); err != nil {
+		return err
+	}
 	bitIndex := 0
 	for _, pixel := range pixels {
 		x, y := pixel.X, pixel.Y
@@ -108,6 +191,9 @@ func (e *LSBEncoder) EncodeData(inputPath, outputPath string, data []byte) error
 		if bitIndex/8 >= len(fullData) {
 			break
 		}
+		if err := t.advance(3); err != nil {
+			return err
+		}
 
 		r, g, b, a := rgbaImg.At(x, y).RGBA()
 
@@ -136,45 +222,21 @@ func (e *LSBEncoder) EncodeData(inputPath, outputPath string, data []byte) error
 		})
 	}
 
-	// Save the output image
-	outFile, err := os.Create(outputPath)
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
-
-	// Use no compression for PNG to minimize file size changes
-	encoder := &png.Encoder{
-		CompressionLevel: png.NoCompression,
-	}
-
-	// If the original was a JPEG, we need to use PNG for lossless storage
-	// but we'll use minimal settings to keep file size down
-	return encoder.Encode(outFile, rgbaImg)
+	return nil
 }
 
-// DecodeData extracts hidden binary data from an image
-func (e *LSBEncoder) DecodeData(inputPath string) ([]byte, error) {
-	// Open the input image
-	file, err := os.Open(inputPath)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	// Decode the image
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return nil, err
-	}
-
+// extractFromImage extracts hidden binary data from the LSBs of an image
+func (e *LSBEncoder) extractFromImage(t *tracker, img image.Image) ([]byte, error) {
 	// Get image bounds
 	bounds := img.Bounds()
 	width, height := bounds.Max.X, bounds.Max.Y
 
 	// Use seed to determine pixel order
 	rng := NewSeededRNG(e.Seed)
-	pixels := generatePixelOrder(width, height, rng)
+	pixels, err := generatePixelOrder(t.span(0, 0.6), width, height, rng)
+	if err != nil {
+		return nil, err
+	}
 
 	// Extract the length first (4 bytes)
 	extractedData := make([]byte, 0)
@@ -182,7 +244,13 @@ func (e *LSBEncoder) DecodeData(inputPath string) ([]byte, error) {
 	currentByte := byte(0)
 	bitsRead := 0
 
+	if err := t.phase(0.6, 1, len(pixels)); err != nil {
+		return nil, err
+	}
 	for _, pixel := range pixels {
+		if err := t.advance(1); err != nil {
+			return nil, err
+		}
 		x, y := pixel.X, pixel.Y
 		r, g, b, _ := img.At(x, y).RGBA()
 
@@ -249,7 +317,7 @@ func NewSeededRNG(seed int64) *mathrand.Rand {
 }
 
 // generatePixelOrder creates a pseudo-random order of pixels based on the seed
-func generatePixelOrder(width, height int, rng *mathrand.Rand) []Pixel {
+func generatePixelOrder(t *tracker, width, height int, rng *mathrand.Rand) ([]Pixel, error) {
 	pixels := make([]Pixel, width*height)
 
 	// Initialize with all pixels
@@ -262,12 +330,38 @@ func generatePixelOrder(width, height int, rng *mathrand.Rand) []Pixel {
 	}
 
 	// Shuffle the pixels using Fisher-Yates algorithm
+	if err := t.phase(0, 1, len(pixels)); err != nil {
+		return nil, err
+	}
 	for i := len(pixels) - 1; i > 0; i-- {
 		j := rng.Intn(i + 1)
 		pixels[i], pixels[j] = pixels[j], pixels[i]
+		if err := t.advance(1); err != nil {
+			return nil, err
+		}
+	}
+
+	return pixels, nil
+}
+
+// generatePermutation returns the same permutation of n as rng.Perm, checking for
+// cancellation as it goes
+func generatePermutation(t *tracker, n int, rng *mathrand.Rand) ([]int, error) {
+	if err := t.phase(0, 1, n); err != nil {
+		return nil, err
+	}
+
+	perm := make([]int, n)
+	for i := 0; i < n; i++ {
+		j := rng.Intn(i + 1)
+		perm[i] = perm[j]
+		perm[j] = i
+		if err := t.advance(1); err != nil {
+			return nil, err
+		}
 	}
 
-	return pixels
+	return perm, nil
 }
 
 // embedBits embeds bits from data into the color value
@@ -0,0 +1,85 @@
+// method.go - Method descriptions and the encoder factory
+package steganography
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Method names
+const (
+	MethodLSB             = "lsb"               // Image LSB
+	MethodBPCS            = "bpcs"              // Image BPCS
+	MethodAudioLSB        = "audio-lsb"         // WAV/AIFF sample LSB
+	MethodVideoBytes      = "video-bytes"       // AVI frame byte LSB
+	MethodVideoFramesLSB  = "video-frames-lsb"  // Per-frame image LSB in AVI or Y4M
+	MethodVideoFramesBPCS = "video-frames-bpcs" // Per-frame image BPCS in AVI or Y4M
+	MethodYUV             = "yuv"               // Y4M plane LSB
+)
+
+// defaultBPCSThreshold is used when a BPCS method leaves the threshold unset
+const defaultBPCSThreshold = 0.45
+
+// Method describes a steganography method together with its settings
+type Method struct {
+	Name                string  `json:"name"`
+	ComplexityThreshold float64 `json:"complexityThreshold,omitempty"` // BPCS methods
+	Plane               string  `json:"plane,omitempty"`               // YUV method
+	BitDepth            int     `json:"bitDepth,omitempty"`            // YUV method
+}
+
+// NewEncoder creates the encoder for a method with the given seed
+func NewEncoder(seed string, method Method) (Embedder, error) {
+	threshold := method.ComplexityThreshold
+	if threshold == 0 {
+		threshold = defaultBPCSThreshold
+	}
+
+	switch method.Name {
+	case MethodLSB:
+		return NewLSBEncoder(seed)
+	case MethodBPCS:
+		return NewBPCSEncoder(seed, threshold)
+	case MethodAudioLSB:
+		return NewAudioEncoder(seed)
+	case MethodVideoBytes:
+		return NewVideoEncoder(seed)
+	case MethodVideoFramesLSB:
+		return NewVideoFrameEncoder(seed, "lsb", threshold)
+	case MethodVideoFramesBPCS:
+		return NewVideoFrameEncoder(seed, "bpcs", threshold)
+	case MethodYUV:
+		return NewYUVEncoder(seed, method.Plane, method.BitDepth)
+	}
+	return nil, errors.New("unknown method: " + method.Name)
+}
+
+// CarrierMethods returns the methods that can be applied to a carrier file, based on its extension
+func CarrierMethods(path string) []Method {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg":
+		methods := []Method{{Name: MethodLSB}}
+		for _, threshold := range []float64{0.3, 0.35, 0.4, 0.45} {
+			methods = append(methods, Method{Name: MethodBPCS, ComplexityThreshold: threshold})
+		}
+		return methods
+	case ".wav", ".rf64", ".bw64", ".aif", ".aiff", ".aifc":
+		return []Method{{Name: MethodAudioLSB}}
+	case ".avi":
+		return []Method{
+			{Name: MethodVideoBytes},
+			{Name: MethodVideoFramesLSB},
+			{Name: MethodVideoFramesBPCS, ComplexityThreshold: defaultBPCSThreshold},
+		}
+	case ".y4m":
+		return []Method{
+			{Name: MethodYUV, Plane: "y", BitDepth: 1},
+			{Name: MethodYUV, Plane: "all", BitDepth: 1},
+			{Name: MethodYUV, Plane: "all", BitDepth: 2},
+			{Name: MethodVideoFramesLSB},
+			{Name: MethodVideoFramesBPCS, ComplexityThreshold: defaultBPCSThreshold},
+		}
+	}
+	return nil
+}
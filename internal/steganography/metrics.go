@@ -0,0 +1,185 @@
+// metrics.go - Quality metrics comparing cover and stego images
+package steganography
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"os"
+)
+
+// maxPSNR is reported for identical images, whose PSNR is infinite
+const maxPSNR = 100.0
+
+// ssimWindow and ssimStride set the sliding window SSIM is averaged over
+const (
+	ssimWindow = 8
+	ssimStride = 4
+)
+
+// ImageMetrics holds the differences between a cover image and a stego image
+type ImageMetrics struct {
+	Width         int     `json:"width"`
+	Height        int     `json:"height"`
+	MSE           float64 `json:"mse"`  // Mean squared error over the RGB channels
+	PSNR          float64 `json:"psnr"` // Peak signal-to-noise ratio in dB, capped at 100 for identical images
+	SSIM          float64 `json:"ssim"` // Mean structural similarity of the luma
+	ChangedPixels int     `json:"changedPixels"`
+	TotalPixels   int     `json:"totalPixels"`
+}
+
+// CompareImages computes the quality metrics between two images of the same size
+func CompareImages(cover, stego image.Image) (*ImageMetrics, error) {
+	coverRGBA, stegoRGBA := toRGBA(cover), toRGBA(stego)
+	bounds := coverRGBA.Bounds()
+	if bounds != stegoRGBA.Bounds() {
+		return nil, errors.New("images have different dimensions")
+	}
+
+	width, height := bounds.Dx(), bounds.Dy()
+	metrics := &ImageMetrics{
+		Width:       width,
+		Height:      height,
+		TotalPixels: width * height,
+	}
+	if metrics.TotalPixels == 0 {
+		return nil, errors.New("images are empty")
+	}
+
+	// Squared error and changed pixels
+	squaredError := 0.0
+	for i := 0; i < len(coverRGBA.Pix); i += 4 {
+		changed := false
+		for c := 0; c < 4; c++ {
+			d := float64(coverRGBA.Pix[i+c]) - float64(stegoRGBA.Pix[i+c])
+			if d != 0 {
+				changed = true
+			}
+			if c < 3 {
+				squaredError += d * d
+			}
+		}
+		if changed {
+			metrics.ChangedPixels++
+		}
+	}
+	metrics.MSE = squaredError / float64(3*metrics.TotalPixels)
+
+	if metrics.MSE == 0 {
+		metrics.PSNR = maxPSNR
+	} else {
+		metrics.PSNR = math.Min(maxPSNR, 10*math.Log10(255*255/metrics.MSE))
+	}
+
+	metrics.SSIM = meanSSIM(luma(coverRGBA), luma(stegoRGBA), width, height)
+	return metrics, nil
+}
+
+// CompareImageFiles computes the quality metrics between two image files
+func CompareImageFiles(coverPath, stegoPath string) (*ImageMetrics, error) {
+	cover, err := decodeImageFile(coverPath)
+	if err != nil {
+		return nil, err
+	}
+	stego, err := decodeImageFile(stegoPath)
+	if err != nil {
+		return nil, err
+	}
+	return CompareImages(cover, stego)
+}
+
+// DifferenceImage renders the absolute per-channel difference of two images of
+// the same size, multiplied by amplify so single-step LSB changes become visible
+func DifferenceImage(cover, stego image.Image, amplify int) (image.Image, error) {
+	coverRGBA, stegoRGBA := toRGBA(cover), toRGBA(stego)
+	bounds := coverRGBA.Bounds()
+	if bounds != stegoRGBA.Bounds() {
+		return nil, errors.New("images have different dimensions")
+	}
+	if amplify < 1 {
+		amplify = 1
+	}
+
+	output := image.NewRGBA(bounds)
+	for i := 0; i < len(coverRGBA.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			d := int(coverRGBA.Pix[i+c]) - int(stegoRGBA.Pix[i+c])
+			if d < 0 {
+				d = -d
+			}
+			output.Pix[i+c] = uint8(min(d*amplify, 255))
+		}
+		output.Pix[i+3] = 0xFF
+	}
+
+	return output, nil
+}
+
+// Helper functions
+
+// decodeImageFile opens and decodes an image file
+func decodeImageFile(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+// luma returns the Rec. 601 luma of each pixel of an RGBA image
+func luma(img *image.RGBA) []float64 {
+	values := make([]float64, len(img.Pix)/4)
+	for i := range values {
+		r, g, b := img.Pix[i*4], img.Pix[i*4+1], img.Pix[i*4+2]
+		values[i] = float64(color.GrayModel.Convert(color.RGBA{r, g, b, 0xFF}).(color.Gray).Y)
+	}
+	return values
+}
+
+// meanSSIM averages the structural similarity of two luma planes over sliding windows
+func meanSSIM(a, b []float64, width, height int) float64 {
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+
+	// Small images are compared as a single window
+	windowW, windowH := min(ssimWindow, width), min(ssimWindow, height)
+	n := float64(windowW * windowH)
+
+	sum, windows := 0.0, 0
+	for y := 0; y+windowH <= height; y += ssimStride {
+		for x := 0; x+windowW <= width; x += ssimStride {
+			var sumA, sumB, sumAA, sumBB, sumAB float64
+			for wy := 0; wy < windowH; wy++ {
+				row := (y+wy)*width + x
+				for wx := 0; wx < windowW; wx++ {
+					va, vb := a[row+wx], b[row+wx]
+					sumA += va
+					sumB += vb
+					sumAA += va * va
+					sumBB += vb * vb
+					sumAB += va * vb
+				}
+			}
+
+			meanA, meanB := sumA/n, sumB/n
+			varA := sumAA/n - meanA*meanA
+			varB := sumBB/n - meanB*meanB
+			covariance := sumAB/n - meanA*meanB
+
+			sum += ((2*meanA*meanB + c1) * (2*covariance + c2)) /
+				((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			windows++
+		}
+	}
+
+	if windows == 0 {
+		return 1
+	}
+	return sum / float64(windows)
+}
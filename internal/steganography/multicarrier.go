@@ -0,0 +1,354 @@
+// multicarrier.go - Splitting one payload across several carriers
+package steganography
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"strconv"
+)
+
+// splitMagic marks a chunk of a split payload
+const splitMagic = "SPLT"
+
+// splitChunkHeaderSize is the size of the header stored with each chunk:
+// [4 bytes magic][16 bytes set ID][4 bytes chunk index][4 bytes chunk count]
+// [4 bytes payload length][32 bytes payload SHA-256][4 bytes chunk CRC32]
+const splitChunkHeaderSize = 68
+
+// SplitCarrier is a carrier file taking part in a split payload
+// An empty method selects the carrier's method with the largest capacity
+type SplitCarrier struct {
+	InputPath  string
+	OutputPath string
+	Method     Method
+}
+
+// SplitPiece describes the chunk stored in one carrier
+type SplitPiece struct {
+	Carrier int    `json:"carrier"` // Index of the carrier in the encoded or decoded list
+	Index   int    `json:"index"`   // Chunk index within the set
+	Size    int    `json:"size"`    // Payload bytes in the chunk
+	Method  Method `json:"method"`
+}
+
+// SplitSet describes a payload split across carriers
+type SplitSet struct {
+	SetID       string       `json:"setId"`
+	Total       int          `json:"total"`
+	PayloadSize int          `json:"payloadSize"`
+	Pieces      []SplitPiece `json:"pieces"`
+}
+
+// SplitRecovery is the result of reassembling a split payload
+type SplitRecovery struct {
+	SetID        string       `json:"setId"`
+	Total        int          `json:"total"`
+	PayloadSize  int          `json:"payloadSize"`
+	Data         []byte       `json:"-"`            // Reassembled payload, only set when Verified
+	Verified     bool         `json:"verified"`     // All chunks found and the payload hash matches
+	Pieces       []SplitPiece `json:"pieces"`       // Chunks found, in carrier order
+	Missing      []int        `json:"missing"`      // Indices of the chunks that were not found
+	Unrecognized []int        `json:"unrecognized"` // Carriers without a chunk of the set
+}
+
+// SplitEncode splits data into one chunk per carrier, sized in proportion to each
+// carrier's capacity, and hides every chunk with its header in its carrier
+func SplitEncode(seed string, carriers []SplitCarrier, data []byte) (*SplitSet, error) {
+	return SplitEncodeContext(context.Background(), seed, carriers, data, nil)
+}
+
+// SplitEncodeContext is SplitEncode with cancellation and progress reporting
+func SplitEncodeContext(ctx context.Context, seed string, carriers []SplitCarrier, data []byte, progress Progress) (*SplitSet, error) {
+	t := newTracker(ctx, progress)
+	if len(carriers) == 0 {
+		return nil, errors.New("no carriers to split the payload across")
+	}
+
+	// Select methods and measure the usable capacity of each carrier
+	usable := make([]int, len(carriers))
+	totalUsable := 0
+	measure := t.span(0, 0.1)
+	for i := range carriers {
+		if err := measure.part(i, len(carriers)).report(0); err != nil {
+			return nil, err
+		}
+		carrier := &carriers[i]
+		if carrier.Method.Name == "" {
+			method, err := largestCapacityMethod(carrier.InputPath)
+			if err != nil {
+				return nil, carrierError(t, i, err)
+			}
+			carrier.Method = method
+		}
+
+		capacity, err := methodCapacity(carrier.InputPath, carrier.Method)
+		if err != nil {
+			return nil, carrierError(t, i, err)
+		}
+		usable[i] = max(capacity-splitChunkHeaderSize, 0)
+		totalUsable += usable[i]
+	}
+
+	if len(data) > totalUsable {
+		return nil, errors.New("payload exceeds the combined capacity of the carriers (" + strconv.Itoa(totalUsable) + " bytes)")
+	}
+
+	// Size the chunks in proportion to the usable capacity, so every carrier is used at the same rate
+	sizes := make([]int, len(carriers))
+	assigned := 0
+	for i := range sizes {
+		if totalUsable > 0 {
+			sizes[i] = int(int64(len(data)) * int64(usable[i]) / int64(totalUsable))
+		}
+		assigned += sizes[i]
+	}
+	for i := 0; assigned < len(data); i = (i + 1) % len(sizes) {
+		if sizes[i] < usable[i] {
+			sizes[i]++
+			assigned++
+		}
+	}
+
+	// Identify the set
+	var setID [16]byte
+	if _, err := rand.Read(setID[:]); err != nil {
+		return nil, err
+	}
+	payloadHash := sha256.Sum256(data)
+
+	set := &SplitSet{
+		SetID:       hex.EncodeToString(setID[:]),
+		Total:       len(carriers),
+		PayloadSize: len(data),
+	}
+
+	offset := 0
+	embed := t.span(0.1, 1)
+	for i, carrier := range carriers {
+		// Build the chunk with its header
+		chunk := make([]byte, splitChunkHeaderSize+sizes[i])
+		copy(chunk[0:4], splitMagic)
+		copy(chunk[4:20], setID[:])
+		binary.BigEndian.PutUint32(chunk[20:24], uint32(i))
+		binary.BigEndian.PutUint32(chunk[24:28], uint32(len(carriers)))
+		binary.BigEndian.PutUint32(chunk[28:32], uint32(len(data)))
+		copy(chunk[32:64], payloadHash[:])
+		copy(chunk[splitChunkHeaderSize:], data[offset:offset+sizes[i]])
+		binary.BigEndian.PutUint32(chunk[64:68], splitChunkChecksum(chunk))
+		offset += sizes[i]
+
+		// Hide the chunk in its carrier
+		encoder, err := NewEncoder(seed, carrier.Method)
+		if err != nil {
+			return nil, err
+		}
+		err = encoder.EncodeDataContext(ctx, carrier.InputPath, carrier.OutputPath, chunk, embed.part(i, len(carriers)).callback())
+		if err != nil {
+			return nil, carrierError(t, i, err)
+		}
+
+		set.Pieces = append(set.Pieces, SplitPiece{Carrier: i, Index: i, Size: sizes[i], Method: carrier.Method})
+	}
+
+	return set, t.report(1)
+}
+
+// SplitDecode extracts the chunks of a split payload from carriers given in any
+// order, trying every method that applies to each carrier, and reassembles the
+// payload when all chunks of the set are present
+func SplitDecode(seed string, carrierPaths []string) (*SplitRecovery, error) {
+	return SplitDecodeContext(context.Background(), seed, carrierPaths, nil)
+}
+
+// SplitDecodeContext is SplitDecode with cancellation and progress reporting
+func SplitDecodeContext(ctx context.Context, seed string, carrierPaths []string, progress Progress) (*SplitRecovery, error) {
+	t := newTracker(ctx, progress)
+	if len(carrierPaths) == 0 {
+		return nil, errors.New("no carriers to reassemble the payload from")
+	}
+
+	// Find the chunk in each carrier
+	type foundChunk struct {
+		piece  SplitPiece
+		header []byte
+		data   []byte
+	}
+	found := make([]*foundChunk, len(carrierPaths))
+	setCounts := make(map[string]int)
+	for i, path := range carrierPaths {
+		chunk, method, ok, err := findEmbeddedData(t.part(i, len(carrierPaths)), seed, path, validSplitChunk)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		found[i] = &foundChunk{
+			piece: SplitPiece{
+				Carrier: i,
+				Index:   int(binary.BigEndian.Uint32(chunk[20:24])),
+				Size:    len(chunk) - splitChunkHeaderSize,
+				Method:  method,
+			},
+			header: chunk[:splitChunkHeaderSize],
+			data:   chunk[splitChunkHeaderSize:],
+		}
+		setCounts[string(chunk[4:20])]++
+	}
+
+	// Reassemble the set with the most chunks
+	var setID string
+	for id, count := range setCounts {
+		if setID == "" || count > setCounts[setID] || (count == setCounts[setID] && id < setID) {
+			setID = id
+		}
+	}
+	if setID == "" {
+		return nil, errors.New("no chunks of a split payload were found")
+	}
+
+	recovery := &SplitRecovery{
+		SetID:        hex.EncodeToString([]byte(setID)),
+		Missing:      []int{},
+		Unrecognized: []int{},
+	}
+	var reference []byte
+	chunks := make(map[int][]byte)
+	for i, chunk := range found {
+		if chunk == nil || string(chunk.header[4:20]) != setID {
+			recovery.Unrecognized = append(recovery.Unrecognized, i)
+			continue
+		}
+
+		// The first chunk sets the expected count, length and hash
+		if reference == nil {
+			reference = chunk.header
+			recovery.Total = int(binary.BigEndian.Uint32(reference[24:28]))
+			recovery.PayloadSize = int(binary.BigEndian.Uint32(reference[28:32]))
+		}
+		if !bytes.Equal(chunk.header[24:64], reference[24:64]) || chunk.piece.Index >= recovery.Total {
+			recovery.Unrecognized = append(recovery.Unrecognized, i)
+			continue
+		}
+
+		recovery.Pieces = append(recovery.Pieces, chunk.piece)
+		if _, ok := chunks[chunk.piece.Index]; !ok {
+			chunks[chunk.piece.Index] = chunk.data
+		}
+	}
+
+	var payload []byte
+	for index := 0; index < recovery.Total; index++ {
+		data, ok := chunks[index]
+		if !ok {
+			recovery.Missing = append(recovery.Missing, index)
+			continue
+		}
+		payload = append(payload, data...)
+	}
+
+	// Verify the reassembled payload against its hash
+	if len(recovery.Missing) == 0 && len(payload) == recovery.PayloadSize {
+		payloadHash := sha256.Sum256(payload)
+		if bytes.Equal(payloadHash[:], reference[32:64]) {
+			recovery.Data = payload
+			recovery.Verified = true
+		}
+	}
+
+	return recovery, t.report(1)
+}
+
+// Helper functions
+
+// splitChunkChecksum computes the CRC32 of a chunk with its checksum field zeroed
+func splitChunkChecksum(chunk []byte) uint32 {
+	crc := crc32.NewIEEE()
+	crc.Write(chunk[:64])
+	crc.Write([]byte{0, 0, 0, 0})
+	crc.Write(chunk[splitChunkHeaderSize:])
+	return crc.Sum32()
+}
+
+// validSplitChunk reports whether data is a chunk of a split payload with a valid checksum
+func validSplitChunk(chunk []byte) bool {
+	if len(chunk) < splitChunkHeaderSize || string(chunk[0:4]) != splitMagic {
+		return false
+	}
+	return binary.BigEndian.Uint32(chunk[64:68]) == splitChunkChecksum(chunk)
+}
+
+// findEmbeddedData tries every method that applies to a carrier and returns the
+// first extracted data that passes the validity check, with the method that found it.
+// It only fails when the work is canceled.
+func findEmbeddedData(t *tracker, seed, path string, valid func([]byte) bool) ([]byte, Method, bool, error) {
+	methods := CarrierMethods(path)
+	for m, method := range methods {
+		encoder, err := NewEncoder(seed, method)
+		if err != nil {
+			continue
+		}
+		data, err := encoder.DecodeDataContext(t.ctx, path, t.part(m, len(methods)).callback())
+		if err == nil && valid(data) {
+			return data, method, true, nil
+		}
+		if err := t.ctx.Err(); err != nil {
+			return nil, Method{}, false, err
+		}
+	}
+	return nil, Method{}, false, nil
+}
+
+// carrierError adds the carrier index to an error, except for a cancellation,
+// which is returned as it is so callers can still recognize it
+func carrierError(t *tracker, i int, err error) error {
+	if ctxErr := t.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return errors.New("carrier " + strconv.Itoa(i) + ": " + err.Error())
+}
+
+// largestCapacityMethod returns the method that holds the most data in a carrier
+func largestCapacityMethod(path string) (Method, error) {
+	methods := CarrierMethods(path)
+	if len(methods) == 0 {
+		return Method{}, errors.New("unsupported carrier format")
+	}
+
+	var best Method
+	bestCapacity := -1
+	for _, method := range methods {
+		capacity, err := methodCapacity(path, method)
+		if err != nil {
+			continue
+		}
+		if capacity > bestCapacity {
+			best, bestCapacity = method, capacity
+		}
+	}
+	if bestCapacity < 0 {
+		return Method{}, errors.New("no method can use the carrier")
+	}
+
+	return best, nil
+}
+
+// methodCapacity returns the capacity of a carrier for a method
+func methodCapacity(path string, method Method) (int, error) {
+	encoder, err := NewEncoder("", method)
+	if err != nil {
+		return 0, err
+	}
+	estimator, ok := encoder.(CapacityEstimator)
+	if !ok {
+		return 0, errors.New("capacity estimation is not supported for " + method.Name)
+	}
+	return estimator.Capacity(path)
+}
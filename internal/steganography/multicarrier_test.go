@@ -0,0 +1,154 @@
+package steganography
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// writeNoisePNG writes an opaque PNG of random pixels to a new file in dir
+func writeNoisePNG(t *testing.T, dir string, width, height int, seed int64) string {
+	t.Helper()
+
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 0xFF})
+		}
+	}
+
+	path := filepath.Join(dir, "cover_"+strconv.FormatInt(seed, 10)+".png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// splitTestSet encodes payload across count noise covers with the LSB method
+// and returns the stego paths in carrier order
+func splitTestSet(t *testing.T, payload []byte, count int) []string {
+	t.Helper()
+
+	dir := t.TempDir()
+	carriers := make([]SplitCarrier, count)
+	for i := range carriers {
+		carriers[i] = SplitCarrier{
+			InputPath:  writeNoisePNG(t, dir, 48+16*i, 48, int64(i+1)),
+			OutputPath: filepath.Join(dir, "stego_"+strconv.Itoa(i)+".png"),
+			Method:     Method{Name: MethodLSB},
+		}
+	}
+
+	set, err := SplitEncode("split-test", carriers, payload)
+	if err != nil {
+		t.Fatalf("SplitEncode: %v", err)
+	}
+	if set.Total != count || len(set.Pieces) != count || set.PayloadSize != len(payload) {
+		t.Fatalf("SplitEncode set = %+v, want %d pieces of a %d byte payload", set, count, len(payload))
+	}
+
+	paths := make([]string, count)
+	for i, carrier := range carriers {
+		paths[i] = carrier.OutputPath
+	}
+	return paths
+}
+
+func TestSplitRoundTripAnyOrder(t *testing.T) {
+	payload := bytes.Repeat([]byte("split payload "), 40)
+	paths := splitTestSet(t, payload, 3)
+
+	for _, order := range [][]int{{0, 1, 2}, {2, 0, 1}, {1, 2, 0}} {
+		shuffled := []string{paths[order[0]], paths[order[1]], paths[order[2]]}
+		recovery, err := SplitDecode("split-test", shuffled)
+		if err != nil {
+			t.Fatalf("order %v: SplitDecode: %v", order, err)
+		}
+		if !recovery.Verified || !bytes.Equal(recovery.Data, payload) {
+			t.Fatalf("order %v: payload not recovered: %+v", order, recovery)
+		}
+		for i, piece := range recovery.Pieces {
+			if piece.Index != order[i] {
+				t.Errorf("order %v: carrier %d holds chunk %d, want %d", order, i, piece.Index, order[i])
+			}
+		}
+	}
+}
+
+func TestSplitDecodeReportsMissingChunks(t *testing.T) {
+	payload := bytes.Repeat([]byte{0xA5, 0x5A}, 300)
+	paths := splitTestSet(t, payload, 3)
+
+	// Replace the middle carrier with an unrelated image
+	unrelated := writeNoisePNG(t, t.TempDir(), 64, 48, 99)
+	recovery, err := SplitDecode("split-test", []string{paths[0], unrelated, paths[2]})
+	if err != nil {
+		t.Fatalf("SplitDecode: %v", err)
+	}
+	if recovery.Verified || recovery.Data != nil {
+		t.Error("an incomplete set must not verify")
+	}
+	if len(recovery.Missing) != 1 || recovery.Missing[0] != 1 {
+		t.Errorf("Missing = %v, want [1]", recovery.Missing)
+	}
+	if len(recovery.Unrecognized) != 1 || recovery.Unrecognized[0] != 1 {
+		t.Errorf("Unrecognized = %v, want [1]", recovery.Unrecognized)
+	}
+}
+
+func TestSplitDecodeWrongSeed(t *testing.T) {
+	paths := splitTestSet(t, []byte("secret"), 2)
+
+	if _, err := SplitDecode("other-seed", paths); err == nil {
+		t.Error("SplitDecode with the wrong seed should find no chunks")
+	}
+}
+
+func TestValidSplitChunk(t *testing.T) {
+	chunk := make([]byte, splitChunkHeaderSize+5)
+	copy(chunk[0:4], splitMagic)
+	binary.BigEndian.PutUint32(chunk[24:28], 1)
+	binary.BigEndian.PutUint32(chunk[28:32], 5)
+	copy(chunk[splitChunkHeaderSize:], "hello")
+	binary.BigEndian.PutUint32(chunk[64:68], splitChunkChecksum(chunk))
+
+	if !validSplitChunk(chunk) {
+		t.Fatal("well-formed chunk rejected")
+	}
+
+	tests := []struct {
+		name  string
+		chunk []byte
+	}{
+		{"short", chunk[:splitChunkHeaderSize-1]},
+		{"bad magic", append([]byte("SPLX"), chunk[4:]...)},
+		{"corrupt header", flipByte(chunk, 22)},
+		{"corrupt data", flipByte(chunk, splitChunkHeaderSize+2)},
+		{"corrupt checksum", flipByte(chunk, 65)},
+	}
+	for _, tt := range tests {
+		if validSplitChunk(tt.chunk) {
+			t.Errorf("%s: chunk accepted", tt.name)
+		}
+	}
+}
+
+// flipByte returns a copy of data with the byte at i inverted
+func flipByte(data []byte, i int) []byte {
+	flipped := append([]byte(nil), data...)
+	flipped[i] ^= 0xFF
+	return flipped
+}
@@ -0,0 +1,123 @@
+// planner.go - Carrier and method selection for a payload
+package steganography
+
+import (
+	"errors"
+	"math"
+)
+
+// Detection sensitivities: the fraction of a method's capacity at which the
+// predicted detectability reaches 1-1/e. These are heuristic guesses that only
+// rank methods against each other (image LSB is the best studied attack target,
+// audio and raw video are noisier covers); they were not measured with
+// EstimateEmbeddingRate, ChiSquareAnalyze or any other detector.
+const (
+	imageLSBSensitivity = 0.05
+	audioLSBSensitivity = 0.1
+	videoLSBSensitivity = 0.1
+)
+
+// PlanOption is one carrier and method evaluated by the planner
+// Detectability is a heuristic 1-exp(-rate/sensitivity) with a fixed sensitivity
+// per method. It orders options but is not the output of a calibrated detector.
+type PlanOption struct {
+	Carrier       int     `json:"carrier"` // Index of the carrier in the planned list
+	Method        Method  `json:"method"`
+	Capacity      int     `json:"capacity"`
+	Fits          bool    `json:"fits"`
+	Rate          float64 `json:"rate"`          // Fraction of the capacity the payload uses
+	Detectability float64 `json:"detectability"` // Heuristic detection score from 0 to 1
+	Error         string  `json:"error,omitempty"`
+}
+
+// Plan is the planner's recommendation for a payload
+type Plan struct {
+	PayloadSize int          `json:"payloadSize"`
+	Best        *PlanOption  `json:"best"` // Nil when no carrier can hold the payload
+	Options     []PlanOption `json:"options"`
+}
+
+// PlanEmbedding evaluates every method available for each carrier file and picks
+// the carrier and method with the lowest predicted detectability that can hold
+// payloadSize bytes. Ties go to the larger capacity.
+func PlanEmbedding(carrierPaths []string, payloadSize int) (*Plan, error) {
+	if len(carrierPaths) == 0 {
+		return nil, errors.New("no carriers to plan for")
+	}
+	if payloadSize < 0 {
+		return nil, errors.New("invalid payload size")
+	}
+
+	plan := &Plan{PayloadSize: payloadSize}
+	for i, path := range carrierPaths {
+		methods := CarrierMethods(path)
+		if len(methods) == 0 {
+			plan.Options = append(plan.Options, PlanOption{Carrier: i, Error: "unsupported carrier format"})
+			continue
+		}
+
+		for _, method := range methods {
+			plan.Options = append(plan.Options, evaluateOption(i, path, method, payloadSize))
+		}
+	}
+
+	// Pick the least detectable option that fits
+	for i := range plan.Options {
+		option := &plan.Options[i]
+		if !option.Fits {
+			continue
+		}
+		if plan.Best == nil || option.Detectability < plan.Best.Detectability ||
+			(option.Detectability == plan.Best.Detectability && option.Capacity > plan.Best.Capacity) {
+			plan.Best = option
+		}
+	}
+
+	return plan, nil
+}
+
+// evaluateOption computes the capacity and predicted detectability of one carrier and method
+func evaluateOption(carrier int, path string, method Method, payloadSize int) PlanOption {
+	option := PlanOption{Carrier: carrier, Method: method}
+
+	capacity, err := methodCapacity(path, method)
+	if err != nil {
+		option.Error = err.Error()
+		return option
+	}
+	option.Capacity = capacity
+
+	option.Fits = option.Capacity > 0 && payloadSize <= option.Capacity
+	if option.Capacity > 0 {
+		option.Rate = math.Min(float64(payloadSize)/float64(option.Capacity), 1)
+	} else {
+		option.Rate = 1
+	}
+	option.Detectability = 1 - math.Exp(-option.Rate/methodSensitivity(method))
+
+	return option
+}
+
+// methodSensitivity returns the capacity fraction at which a method becomes detectable
+func methodSensitivity(method Method) float64 {
+	threshold := method.ComplexityThreshold
+	if threshold == 0 {
+		threshold = defaultBPCSThreshold
+	}
+
+	switch method.Name {
+	case MethodLSB, MethodVideoFramesLSB:
+		return imageLSBSensitivity
+	case MethodAudioLSB:
+		return audioLSBSensitivity
+	case MethodVideoBytes:
+		return videoLSBSensitivity
+	case MethodYUV:
+		// Each extra bit per sample doubles the size of the changes
+		return videoLSBSensitivity / float64(max(method.BitDepth, 1))
+	case MethodBPCS, MethodVideoFramesBPCS:
+		// A higher threshold only replaces noisier blocks, which hides better
+		return threshold / 2
+	}
+	return imageLSBSensitivity
+}
@@ -0,0 +1,97 @@
+// progress.go - Cancellation and progress reporting for long encodes and decodes
+package steganography
+
+import "context"
+
+// Progress receives the fraction of an encode or decode that is done, from 0 to 1
+type Progress func(fraction float64)
+
+// trackerInterval is how many units of work pass between cancellation checks
+const trackerInterval = 1 << 14
+
+// tracker checks for cancellation and reports progress from the main loops of an
+// encoder. The work is split into phases that each cover a range of the progress,
+// and a span of a tracker can be handed to nested work, such as a single frame.
+type tracker struct {
+	ctx      context.Context
+	progress Progress
+	offset   float64 // Maps this tracker's progress onto the caller's
+	scale    float64
+
+	phaseStart, phaseEnd float64
+	total, done          int
+}
+
+// newTracker creates a tracker for a context and an optional progress callback
+func newTracker(ctx context.Context, progress Progress) *tracker {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &tracker{ctx: ctx, progress: progress, scale: 1}
+}
+
+// backgroundTracker returns a tracker that is never canceled and reports nothing
+func backgroundTracker() *tracker {
+	return newTracker(context.Background(), nil)
+}
+
+// phase starts a phase of total units of work covering progress from start to end
+// It returns the context's error if the work has been canceled.
+func (t *tracker) phase(start, end float64, total int) error {
+	t.phaseStart, t.phaseEnd = start, end
+	t.total, t.done = total, 0
+	return t.report(start)
+}
+
+// advance records n units of work in the current phase, checking for cancellation
+// and reporting progress every trackerInterval units
+func (t *tracker) advance(n int) error {
+	before := t.done
+	t.done += n
+	if t.done/trackerInterval == before/trackerInterval {
+		return nil
+	}
+
+	fraction := t.phaseEnd
+	if t.total > 0 && t.done < t.total {
+		fraction = t.phaseStart + (t.phaseEnd-t.phaseStart)*float64(t.done)/float64(t.total)
+	}
+	return t.report(fraction)
+}
+
+// report checks for cancellation and reports a progress fraction of this tracker
+func (t *tracker) report(fraction float64) error {
+	if err := t.ctx.Err(); err != nil {
+		return err
+	}
+	if t.progress != nil {
+		t.progress(t.offset + t.scale*fraction)
+	}
+	return nil
+}
+
+// span returns a tracker for nested work covering progress from start to end of this one
+func (t *tracker) span(start, end float64) *tracker {
+	return &tracker{
+		ctx:      t.ctx,
+		progress: t.progress,
+		offset:   t.offset + t.scale*start,
+		scale:    t.scale * (end - start),
+	}
+}
+
+// part returns the span of the i-th of n equal parts of this tracker, for work
+// split over several carriers
+func (t *tracker) part(i, n int) *tracker {
+	return t.span(float64(i)/float64(n), float64(i+1)/float64(n))
+}
+
+// callback returns a Progress that reports through this tracker, so a span can
+// be handed to an encoder's Context method
+func (t *tracker) callback() Progress {
+	return func(fraction float64) {
+		if t.progress != nil {
+			t.progress(t.offset + t.scale*fraction)
+		}
+	}
+}
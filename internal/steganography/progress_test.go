@@ -0,0 +1,116 @@
+package steganography
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGeneratePermutationMatchesPerm(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 50000} {
+		perm, err := generatePermutation(backgroundTracker(), n, NewSeededRNG(42))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := NewSeededRNG(42).Perm(n); !slices.Equal(perm, want) {
+			t.Errorf("n=%d: permutation differs from rng.Perm", n)
+		}
+	}
+}
+
+func TestContextVariants(t *testing.T) {
+	dir := t.TempDir()
+	cover := writeNoisePNG(t, dir, 64, 64, 1)
+	img, err := decodeImageFile(cover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	largeImg := syntheticPhoto(512, 512, 1)
+	carriers := func() []SplitCarrier {
+		return []SplitCarrier{
+			{InputPath: cover, OutputPath: filepath.Join(dir, "out_0.png"), Method: Method{Name: MethodLSB}},
+			{InputPath: writeNoisePNG(t, dir, 64, 64, 2), OutputPath: filepath.Join(dir, "out_1.png"), Method: Method{Name: MethodLSB}},
+		}
+	}
+
+	deniable, _ := NewDeniableEncoder("context-test")
+	watermark, _ := NewWatermarkEncoder("context-test", 0)
+	fragile, _ := NewFragileEncoder("context-test")
+	marked, err := watermark.EmbedImage(largeImg, []byte("id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode cases read the output of the encode case before them
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, progress Progress) error
+	}{
+		{"deniable encode", func(ctx context.Context, progress Progress) error {
+			return deniable.EncodeContext(ctx, cover, filepath.Join(dir, "deniable.png"), []byte("data"), "pw", progress)
+		}},
+		{"deniable decode", func(ctx context.Context, progress Progress) error {
+			_, err := deniable.DecodeContext(ctx, filepath.Join(dir, "deniable.png"), "pw", progress)
+			return err
+		}},
+		{"watermark embed", func(ctx context.Context, progress Progress) error {
+			_, err := watermark.EmbedImageContext(ctx, largeImg, []byte("id"), progress)
+			return err
+		}},
+		{"watermark extract", func(ctx context.Context, progress Progress) error {
+			_, err := watermark.ExtractImageContext(ctx, marked, progress)
+			return err
+		}},
+		{"fragile embed", func(ctx context.Context, progress Progress) error {
+			_, err := fragile.EmbedImageContext(ctx, img, progress)
+			return err
+		}},
+		{"fragile verify", func(ctx context.Context, progress Progress) error {
+			_, err := fragile.VerifyImageContext(ctx, img, progress)
+			return err
+		}},
+		{"split encode", func(ctx context.Context, progress Progress) error {
+			_, err := SplitEncodeContext(ctx, "context-test", carriers(), []byte("split"), progress)
+			return err
+		}},
+		{"split decode", func(ctx context.Context, progress Progress) error {
+			_, err := SplitDecodeContext(ctx, "context-test", []string{filepath.Join(dir, "out_0.png"), filepath.Join(dir, "out_1.png")}, progress)
+			return err
+		}},
+		{"share encode", func(ctx context.Context, progress Progress) error {
+			_, err := ShareEncodeContext(ctx, "context-test", carriers(), []byte("share"), 2, progress)
+			return err
+		}},
+		{"share decode", func(ctx context.Context, progress Progress) error {
+			_, err := ShareDecodeContext(ctx, "context-test", []string{filepath.Join(dir, "out_0.png"), filepath.Join(dir, "out_1.png")}, progress)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Progress rises to 1
+			var reported []float64
+			if err := tt.run(context.Background(), func(fraction float64) { reported = append(reported, fraction) }); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if len(reported) == 0 || reported[len(reported)-1] != 1 {
+				t.Errorf("progress did not finish at 1: %v", reported)
+			}
+			for i := 1; i < len(reported); i++ {
+				if reported[i] < reported[i-1] {
+					t.Errorf("progress went back from %v to %v", reported[i-1], reported[i])
+					break
+				}
+			}
+
+			// A canceled context stops the work with its error
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if err := tt.run(ctx, nil); !errors.Is(err, context.Canceled) {
+				t.Errorf("canceled run: err = %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
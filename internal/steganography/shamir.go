@@ -0,0 +1,364 @@
+// shamir.go - Threshold secret sharing over GF(256) across stego carriers
+package steganography
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"strconv"
+)
+
+// shareMagic marks a secret share
+const shareMagic = "SHAR"
+
+// shareHeaderSize is the size of the header stored with each share:
+// [4 bytes magic][16 bytes set ID][1 byte x coordinate][1 byte threshold]
+// [1 byte share count][1 byte reserved][4 bytes shared length][4 bytes share CRC32]
+// The shared data is the secret followed by its SHA-256, so the hash is as hidden
+// as the secret itself.
+const shareHeaderSize = 32
+
+// GF(256) log and exp tables for the AES polynomial x^8 + x^4 + x^3 + x + 1
+var gfExp, gfLog = gfTables()
+
+// SharePiece describes the share stored in one carrier
+type SharePiece struct {
+	Carrier int    `json:"carrier"` // Index of the carrier in the encoded or decoded list
+	X       int    `json:"x"`       // Share x coordinate
+	Method  Method `json:"method"`
+}
+
+// ShareSet describes a secret shared across carriers
+type ShareSet struct {
+	SetID      string       `json:"setId"`
+	Threshold  int          `json:"threshold"`
+	Shares     int          `json:"shares"`
+	SecretSize int          `json:"secretSize"`
+	Pieces     []SharePiece `json:"pieces"`
+}
+
+// ShareRecovery is the result of combining shares
+type ShareRecovery struct {
+	SetID        string       `json:"setId"`
+	Threshold    int          `json:"threshold"`
+	Shares       int          `json:"shares"`
+	Data         []byte       `json:"-"`            // Reconstructed secret, only set when Verified
+	Verified     bool         `json:"verified"`     // Enough shares found and the secret hash matches
+	Pieces       []SharePiece `json:"pieces"`       // Shares found, in carrier order
+	Unrecognized []int        `json:"unrecognized"` // Carriers without a share of the set
+}
+
+// ShamirSplit splits a secret into n shares, any k of which reconstruct it
+// Each share is its x coordinate followed by one byte per secret byte
+func ShamirSplit(secret []byte, n, k int) ([][]byte, error) {
+	if k < 2 || k > n || n > 255 {
+		return nil, errors.New("threshold must be between 2 and the share count, with at most 255 shares")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+
+	// One random polynomial of degree k-1 per secret byte, with the byte as constant term
+	coefficients := make([]byte, k)
+	for b, value := range secret {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		coefficients[0] = value
+
+		for i := range shares {
+			// Horner's method at x
+			x := shares[i][0]
+			var y byte
+			for c := k - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coefficients[c]
+			}
+			shares[i][b+1] = y
+		}
+	}
+
+	return shares, nil
+}
+
+// ShamirCombine reconstructs a secret from shares produced by ShamirSplit
+// The result is only correct when at least the threshold number of shares is given
+func ShamirCombine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least two shares are needed")
+	}
+
+	length := len(shares[0])
+	seen := make(map[byte]bool)
+	for _, share := range shares {
+		if len(share) != length || length < 1 {
+			return nil, errors.New("shares have different lengths")
+		}
+		if share[0] == 0 || seen[share[0]] {
+			return nil, errors.New("shares have invalid or duplicate x coordinates")
+		}
+		seen[share[0]] = true
+	}
+
+	// Lagrange basis polynomials evaluated at x = 0
+	basis := make([]byte, len(shares))
+	for i := range shares {
+		basis[i] = 1
+		for j := range shares {
+			if i != j {
+				// l_i(0) = prod x_j / (x_j - x_i); subtraction is XOR in GF(256)
+				basis[i] = gfMul(basis[i], gfDiv(shares[j][0], shares[j][0]^shares[i][0]))
+			}
+		}
+	}
+
+	secret := make([]byte, length-1)
+	for b := range secret {
+		var value byte
+		for i, share := range shares {
+			value ^= gfMul(share[b+1], basis[i])
+		}
+		secret[b] = value
+	}
+
+	return secret, nil
+}
+
+// ShareEncode splits data into one share per carrier with a threshold of k and
+// hides every share in its carrier. An empty carrier method selects the least
+// detectable method that holds the share.
+func ShareEncode(seed string, carriers []SplitCarrier, data []byte, k int) (*ShareSet, error) {
+	return ShareEncodeContext(context.Background(), seed, carriers, data, k, nil)
+}
+
+// ShareEncodeContext is ShareEncode with cancellation and progress reporting
+func ShareEncodeContext(ctx context.Context, seed string, carriers []SplitCarrier, data []byte, k int, progress Progress) (*ShareSet, error) {
+	t := newTracker(ctx, progress)
+
+	// Append the hash so the combined secret can be verified
+	hash := sha256.Sum256(data)
+	shared := append(append([]byte{}, data...), hash[:]...)
+
+	shares, err := ShamirSplit(shared, len(carriers), k)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identify the set
+	var setID [16]byte
+	if _, err := rand.Read(setID[:]); err != nil {
+		return nil, err
+	}
+
+	set := &ShareSet{
+		SetID:      hex.EncodeToString(setID[:]),
+		Threshold:  k,
+		Shares:     len(carriers),
+		SecretSize: len(data),
+	}
+
+	for i, carrier := range carriers {
+		// Build the share with its header
+		share := make([]byte, shareHeaderSize+len(shared))
+		copy(share[0:4], shareMagic)
+		copy(share[4:20], setID[:])
+		share[20] = shares[i][0]
+		share[21] = byte(k)
+		share[22] = byte(len(carriers))
+		binary.BigEndian.PutUint32(share[24:28], uint32(len(shared)))
+		copy(share[shareHeaderSize:], shares[i][1:])
+		binary.BigEndian.PutUint32(share[28:32], shareChecksum(share))
+
+		// Pick a method that holds the whole share
+		span := t.part(i, len(carriers))
+		if err := span.report(0); err != nil {
+			return nil, err
+		}
+		if carrier.Method.Name == "" {
+			plan, err := PlanEmbedding([]string{carrier.InputPath}, len(share))
+			if err != nil {
+				return nil, err
+			}
+			if plan.Best == nil {
+				return nil, errors.New("carrier " + strconv.Itoa(i) + " is too small for a share of " + strconv.Itoa(len(share)) + " bytes")
+			}
+			carrier.Method = plan.Best.Method
+		}
+
+		// Hide the share in its carrier
+		encoder, err := NewEncoder(seed, carrier.Method)
+		if err != nil {
+			return nil, err
+		}
+		if err := encoder.EncodeDataContext(ctx, carrier.InputPath, carrier.OutputPath, share, span.callback()); err != nil {
+			return nil, carrierError(t, i, err)
+		}
+
+		set.Pieces = append(set.Pieces, SharePiece{Carrier: i, X: int(shares[i][0]), Method: carrier.Method})
+	}
+
+	return set, t.report(1)
+}
+
+// ShareDecode extracts shares from carriers given in any order and reconstructs
+// the secret when at least the threshold number of shares of one set is present
+func ShareDecode(seed string, carrierPaths []string) (*ShareRecovery, error) {
+	return ShareDecodeContext(context.Background(), seed, carrierPaths, nil)
+}
+
+// ShareDecodeContext is ShareDecode with cancellation and progress reporting
+func ShareDecodeContext(ctx context.Context, seed string, carrierPaths []string, progress Progress) (*ShareRecovery, error) {
+	t := newTracker(ctx, progress)
+	if len(carrierPaths) == 0 {
+		return nil, errors.New("no carriers to combine shares from")
+	}
+
+	// Find the share in each carrier
+	found := make([][]byte, len(carrierPaths))
+	methods := make([]Method, len(carrierPaths))
+	setCounts := make(map[string]int)
+	for i, path := range carrierPaths {
+		share, method, ok, err := findEmbeddedData(t.part(i, len(carrierPaths)), seed, path, validShare)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		found[i], methods[i] = share, method
+		setCounts[string(share[4:20])]++
+	}
+
+	// Combine the set with the most shares
+	var setID string
+	for id, count := range setCounts {
+		if setID == "" || count > setCounts[setID] || (count == setCounts[setID] && id < setID) {
+			setID = id
+		}
+	}
+	if setID == "" {
+		return nil, errors.New("no secret shares were found")
+	}
+
+	recovery := &ShareRecovery{
+		SetID:        hex.EncodeToString([]byte(setID)),
+		Unrecognized: []int{},
+	}
+	var reference []byte
+	var points [][]byte
+	seen := make(map[byte]bool)
+	for i, share := range found {
+		if share == nil || string(share[4:20]) != setID {
+			recovery.Unrecognized = append(recovery.Unrecognized, i)
+			continue
+		}
+
+		// The first share sets the expected threshold, count and length
+		if reference == nil {
+			reference = share
+			recovery.Threshold = int(share[21])
+			recovery.Shares = int(share[22])
+		}
+		if !bytes.Equal(share[21:28], reference[21:28]) {
+			recovery.Unrecognized = append(recovery.Unrecognized, i)
+			continue
+		}
+
+		recovery.Pieces = append(recovery.Pieces, SharePiece{Carrier: i, X: int(share[20]), Method: methods[i]})
+		if !seen[share[20]] {
+			seen[share[20]] = true
+			points = append(points, append([]byte{share[20]}, share[shareHeaderSize:]...))
+		}
+	}
+
+	if len(points) < recovery.Threshold {
+		return recovery, t.report(1)
+	}
+
+	// Combine exactly threshold shares and verify the secret hash
+	shared, err := ShamirCombine(points[:recovery.Threshold])
+	if err != nil {
+		return nil, err
+	}
+	if len(shared) >= sha256.Size {
+		secret := shared[:len(shared)-sha256.Size]
+		hash := sha256.Sum256(secret)
+		if bytes.Equal(hash[:], shared[len(secret):]) {
+			recovery.Data = secret
+			recovery.Verified = true
+		}
+	}
+
+	return recovery, t.report(1)
+}
+
+// Helper functions
+
+// gfTables builds the GF(256) exp and log tables using generator 3
+func gfTables() ([510]byte, [256]byte) {
+	var exp [510]byte
+	var log [256]byte
+
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i] = x
+		exp[i+255] = x
+		log[x] = byte(i)
+
+		// Multiply by the generator 3 = x + 1
+		high := x & 0x80
+		doubled := x << 1
+		if high != 0 {
+			doubled ^= 0x1B
+		}
+		x ^= doubled
+	}
+
+	return exp, log
+}
+
+// gfMul multiplies two elements of GF(256)
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfDiv divides two elements of GF(256); b must not be zero
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// shareChecksum computes the CRC32 of a share with its checksum field zeroed
+func shareChecksum(share []byte) uint32 {
+	crc := crc32.NewIEEE()
+	crc.Write(share[:28])
+	crc.Write([]byte{0, 0, 0, 0})
+	crc.Write(share[shareHeaderSize:])
+	return crc.Sum32()
+}
+
+// validShare reports whether data is a secret share with a valid header and checksum
+func validShare(share []byte) bool {
+	if len(share) < shareHeaderSize || string(share[0:4]) != shareMagic {
+		return false
+	}
+	if share[20] == 0 || share[21] < 2 || share[21] > share[22] {
+		return false
+	}
+	if int(binary.BigEndian.Uint32(share[24:28])) != len(share)-shareHeaderSize {
+		return false
+	}
+	return binary.BigEndian.Uint32(share[28:32]) == shareChecksum(share)
+}
@@ -0,0 +1,189 @@
+package steganography
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// subsets returns every k-element subset of {0, ..., n-1} in lexicographic order
+func subsets(n, k int) [][]int {
+	var result [][]int
+	var walk func(start int, current []int)
+	walk = func(start int, current []int) {
+		if len(current) == k {
+			result = append(result, append([]int(nil), current...))
+			return
+		}
+		for i := start; i < n; i++ {
+			walk(i+1, append(current, i))
+		}
+	}
+	walk(0, nil)
+	return result
+}
+
+func TestShamirEveryThresholdSubset(t *testing.T) {
+	secret := []byte("threshold secret \x00\x01\xfe\xff")
+	const n = 5
+
+	for k := 2; k <= n; k++ {
+		t.Run("k="+strconv.Itoa(k), func(t *testing.T) {
+			shares, err := ShamirSplit(secret, n, k)
+			if err != nil {
+				t.Fatalf("ShamirSplit: %v", err)
+			}
+			if len(shares) != n {
+				t.Fatalf("got %d shares, want %d", len(shares), n)
+			}
+
+			for _, subset := range subsets(n, k) {
+				picked := make([][]byte, len(subset))
+				for i, index := range subset {
+					picked[i] = shares[index]
+				}
+				combined, err := ShamirCombine(picked)
+				if err != nil {
+					t.Fatalf("subset %v: ShamirCombine: %v", subset, err)
+				}
+				if !bytes.Equal(combined, secret) {
+					t.Errorf("subset %v: got %q, want %q", subset, combined, secret)
+				}
+			}
+		})
+	}
+}
+
+func TestShamirBelowThreshold(t *testing.T) {
+	secret := bytes.Repeat([]byte("below threshold "), 4)
+	shares, err := ShamirSplit(secret, 4, 3)
+	if err != nil {
+		t.Fatalf("ShamirSplit: %v", err)
+	}
+
+	// k-1 shares combine to a value unrelated to the secret
+	for _, subset := range subsets(4, 2) {
+		combined, err := ShamirCombine([][]byte{shares[subset[0]], shares[subset[1]]})
+		if err != nil {
+			t.Fatalf("subset %v: ShamirCombine: %v", subset, err)
+		}
+		if bytes.Equal(combined, secret) {
+			t.Errorf("subset %v: two of three shares revealed the secret", subset)
+		}
+	}
+}
+
+func TestShamirSplitRejectsBadParameters(t *testing.T) {
+	tests := []struct {
+		n, k int
+	}{
+		{3, 1},
+		{3, 4},
+		{256, 2},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if _, err := ShamirSplit([]byte("x"), tt.n, tt.k); err == nil {
+			t.Errorf("ShamirSplit(n=%d, k=%d) succeeded", tt.n, tt.k)
+		}
+	}
+}
+
+func TestShamirCombineRejectsInvalidShares(t *testing.T) {
+	shares, err := ShamirSplit([]byte("duplicate"), 3, 2)
+	if err != nil {
+		t.Fatalf("ShamirSplit: %v", err)
+	}
+
+	zeroX := append([]byte(nil), shares[1]...)
+	zeroX[0] = 0
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{"single share", [][]byte{shares[0]}},
+		{"duplicate x", [][]byte{shares[0], shares[0]}},
+		{"duplicate x with other data", [][]byte{shares[0], append([]byte{shares[0][0]}, shares[1][1:]...)}},
+		{"zero x", [][]byte{shares[0], zeroX}},
+		{"different lengths", [][]byte{shares[0], shares[1][:len(shares[1])-1]}},
+		{"empty shares", [][]byte{{}, {}}},
+	}
+	for _, tt := range tests {
+		if _, err := ShamirCombine(tt.shares); err == nil {
+			t.Errorf("%s: ShamirCombine succeeded", tt.name)
+		}
+	}
+}
+
+// shareTestSet encodes secret as n shares with threshold k in noise covers with
+// the LSB method and returns the stego paths in carrier order
+func shareTestSet(t *testing.T, secret []byte, n, k int) []string {
+	t.Helper()
+
+	dir := t.TempDir()
+	carriers := make([]SplitCarrier, n)
+	for i := range carriers {
+		carriers[i] = SplitCarrier{
+			InputPath:  writeNoisePNG(t, dir, 48, 48, int64(i+1)),
+			OutputPath: filepath.Join(dir, "share_"+strconv.Itoa(i)+".png"),
+			Method:     Method{Name: MethodLSB},
+		}
+	}
+
+	set, err := ShareEncode("share-test", carriers, secret, k)
+	if err != nil {
+		t.Fatalf("ShareEncode: %v", err)
+	}
+	if set.Threshold != k || set.Shares != n || len(set.Pieces) != n {
+		t.Fatalf("ShareEncode set = %+v, want %d of %d shares", set, k, n)
+	}
+
+	paths := make([]string, n)
+	for i, carrier := range carriers {
+		paths[i] = carrier.OutputPath
+	}
+	return paths
+}
+
+func TestShareDecodeThreshold(t *testing.T) {
+	secret := []byte("shared across carriers")
+	paths := shareTestSet(t, secret, 4, 3)
+
+	// Any three carriers, in any order, recover the secret
+	for _, subset := range subsets(4, 3) {
+		picked := []string{paths[subset[2]], paths[subset[0]], paths[subset[1]]}
+		recovery, err := ShareDecode("share-test", picked)
+		if err != nil {
+			t.Fatalf("subset %v: ShareDecode: %v", subset, err)
+		}
+		if !recovery.Verified || !bytes.Equal(recovery.Data, secret) {
+			t.Errorf("subset %v: secret not recovered: %+v", subset, recovery)
+		}
+	}
+}
+
+func TestShareDecodeFewerThanThreshold(t *testing.T) {
+	paths := shareTestSet(t, []byte("needs three shares"), 4, 3)
+
+	recovery, err := ShareDecode("share-test", paths[1:3])
+	if err != nil {
+		t.Fatalf("ShareDecode: %v", err)
+	}
+	if recovery.Verified || recovery.Data != nil {
+		t.Error("two of three shares must not recover the secret")
+	}
+	if recovery.Threshold != 3 || recovery.Shares != 4 || len(recovery.Pieces) != 2 {
+		t.Errorf("recovery = %+v, want 2 pieces of a 3-of-4 set", recovery)
+	}
+
+	// The same share twice still counts once
+	recovery, err = ShareDecode("share-test", []string{paths[0], paths[0], paths[2]})
+	if err != nil {
+		t.Fatalf("ShareDecode: %v", err)
+	}
+	if recovery.Verified {
+		t.Error("a repeated share must not count toward the threshold")
+	}
+}
@@ -7,8 +7,34 @@ import (
 	"crypto/sha256"
 	"errors"
 	"io"
+	"strconv"
 )
 
+// parseSeed converts a seed string to an int64 seed
+// An empty seed gives -1; a non-numeric seed is hashed
+func parseSeed(seed string) int64 {
+	var seedInt int64 = -1 // Default to -1
+
+	if seed != "" {
+		// Convert seed string to int64
+		var err error
+		seedInt, err = strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			// If not a number, use string hash as seed
+			h := 0
+			for i := 0; i < len(seed); i++ {
+				h = 31*h + int(seed[i])
+			}
+			seedInt = int64(h)
+		}
+	}
+
+	return seedInt
+}
+
+// EncryptionOverhead is the number of bytes EncryptData adds: a 12-byte GCM nonce and a 16-byte tag
+const EncryptionOverhead = 28
+
 // EncryptData encrypts data using AES-GCM with the provided password
 func EncryptData(data []byte, password string) ([]byte, error) {
 	// Create a new AES cipher using the password
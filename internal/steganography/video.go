@@ -1,72 +1,65 @@
 package steganography
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"math/rand"
 	"os"
-	"strconv"
 )
 
-// VideoEncoder handles LSB steganography for AVI files
+// VideoEncoder handles LSB steganography for uncompressed AVI video frames
 type VideoEncoder struct {
 	Seed int64
 }
 
 // NewVideoEncoder creates a new video steganography encoder with the given seed
 func NewVideoEncoder(seed string) (*VideoEncoder, error) {
-	var seedInt int64 = -1 // Default to -1 (sequential)
-
-	if seed != "" {
-		// Convert seed string to int64
-		var err error
-		seedInt, err = strconv.ParseInt(seed, 10, 64)
-		if err != nil {
-			// If not a number, use string hash as seed
-			h := 0
-			for i := 0; i < len(seed); i++ {
-				h = 31*h + int(seed[i])
-			}
-			seedInt = int64(h)
-		}
-	}
+	seedInt := parseSeed(seed)
 
 	return &VideoEncoder{
 		Seed: seedInt,
 	}, nil
 }
 
-// EncodeMessage embeds a text message into an AVI file using LSB steganography
-func (e *VideoEncoder) EncodeMessage(inputPath, outputPath, message string) error {
+// EncodeData embeds binary data into the uncompressed frames of an AVI file
+// using LSB steganography. Only frame pixel data is modified, so chunk headers,
+// audio chunks and the index stay intact.
+func (e *VideoEncoder) EncodeData(inputPath, outputPath string, data []byte) error {
+	return e.EncodeDataContext(context.Background(), inputPath, outputPath, data, nil)
+}
+
+// EncodeDataContext is EncodeData with cancellation and progress reporting
+func (e *VideoEncoder) EncodeDataContext(ctx context.Context, inputPath, outputPath string, data []byte, progress Progress) error {
+	t := newTracker(ctx, progress)
+
 	// Read original file directly to avoid modifying header structure
 	originalData, err := os.ReadFile(inputPath)
 	if err != nil {
 		return err
 	}
 
-	// Validate AVI file
-	if len(originalData) < 12 || string(originalData[0:4]) != "RIFF" || string(originalData[8:12]) != "AVI " {
-		return errors.New("not a valid AVI file")
-	}
-
-	// Find movi data chunk
-	moviOffset, moviLength, err := findMoviChunk(originalData)
+	// Locate the uncompressed video frames
+	avi, err := parseAVI(originalData)
 	if err != nil {
 		return err
 	}
+	frames := newFrameBytes(avi.frameChunks())
+	if frames.total == 0 {
+		return errors.New("no uncompressed video frames found")
+	}
+
+	// Only frame pixels change, so a sound input gives a playable output
+	if err := avi.validate(originalData); err != nil {
+		return errors.New("input video failed AVI validation: " + err.Error())
+	}
 
 	// Copy the original data
 	outputData := make([]byte, len(originalData))
 	copy(outputData, originalData)
 
-	// Get the actual video data part (inside movi chunk)
-	videoData := outputData[moviOffset : moviOffset+moviLength]
-
-	// Convert message to bytes
-	data := []byte(message)
-
-	// Calculate capacity (1 bit per byte)
-	capacityBits := len(videoData)
+	// Calculate capacity (1 bit per frame byte)
+	capacityBits := frames.total
 	messageBits := len(data)*8 + 32 // 32 bits for length
 
 	if messageBits > capacityBits {
@@ -79,146 +72,144 @@ func (e *VideoEncoder) EncodeMessage(inputPath, outputPath, message string) erro
 	copy(fullData[4:], data)
 
 	// Generate pixel indices based on seed
-	indices := generatePixelOrderVid(len(videoData), len(fullData)*8, e.Seed)
+	indices, err := generatePixelOrderVid(t.span(0.1, 0.8), frames.total, len(fullData)*8, e.Seed)
+	if err != nil {
+		return err
+	}
 
 	// Embed data
+	if err := t.phase(0.8, 0.9, len(fullData)); err != nil {
+		return err
+	}
 	bitIndex := 0
 	for i := 0; i < len(fullData); i++ {
+		if err := t.advance(1); err != nil {
+			return err
+		}
 		byteVal := fullData[i]
 		for b := 0; b < 8; b++ {
 			bit := (byteVal >> (7 - b)) & 1
-			pixelIndex := indices[bitIndex]
-			if pixelIndex >= len(videoData) {
-				return errors.New("pixel index out of bounds")
-			}
+			offset := frames.offset(indices[bitIndex])
 			// Clear LSB and set to message bit
-			videoData[pixelIndex] = (videoData[pixelIndex] & 0xFE) | bit
+			outputData[offset] = (outputData[offset] & 0xFE) | bit
 			bitIndex++
 		}
 	}
 
 	// Write the modified file
-	return os.WriteFile(outputPath, outputData, 0644)
+	if err := os.WriteFile(outputPath, outputData, 0644); err != nil {
+		return err
+	}
+	return t.report(1)
 }
 
-// DecodeMessage extracts a hidden text message from an AVI file
-func (e *VideoEncoder) DecodeMessage(inputPath string) (string, error) {
+// Capacity returns the largest payload in bytes that EncodeData can hide in the AVI file
+func (e *VideoEncoder) Capacity(inputPath string) (int, error) {
 	// Read the entire AVI file
 	fileData, err := os.ReadFile(inputPath)
 	if err != nil {
-		return "", err
+		return 0, err
 	}
 
-	// Validate AVI file
-	if len(fileData) < 12 || string(fileData[0:4]) != "RIFF" || string(fileData[8:12]) != "AVI " {
-		return "", errors.New("not a valid AVI file")
+	// Locate the uncompressed video frames
+	avi, err := parseAVI(fileData)
+	if err != nil {
+		return 0, err
 	}
+	frames := newFrameBytes(avi.frameChunks())
+
+	// 1 bit per frame byte, minus the 32-bit length
+	return max((frames.total-32)/8, 0), nil
+}
+
+// DecodeData extracts hidden binary data from the frames of an AVI file
+func (e *VideoEncoder) DecodeData(inputPath string) ([]byte, error) {
+	return e.DecodeDataContext(context.Background(), inputPath, nil)
+}
+
+// DecodeDataContext is DecodeData with cancellation and progress reporting
+func (e *VideoEncoder) DecodeDataContext(ctx context.Context, inputPath string, progress Progress) ([]byte, error) {
+	t := newTracker(ctx, progress)
 
-	// Find movi data chunk
-	moviOffset, moviLength, err := findMoviChunk(fileData)
+	// Read the entire AVI file
+	fileData, err := os.ReadFile(inputPath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Get video data
-	videoData := fileData[moviOffset : moviOffset+moviLength]
+	// Locate the uncompressed video frames
+	avi, err := parseAVI(fileData)
+	if err != nil {
+		return nil, err
+	}
+	frames := newFrameBytes(avi.frameChunks())
+	if frames.total < 32 {
+		return nil, errors.New("no uncompressed video frames found")
+	}
 
 	// Generate pixel indices based on seed
-	indices := generatePixelOrderVid(len(videoData), 32, e.Seed) // Start with enough for length
-
-	// Validate indices
-	for _, idx := range indices {
-		if idx >= len(videoData) {
-			return "", errors.New("pixel index out of bounds during decoding")
-		}
+	indices, err := generatePixelOrderVid(t.span(0.1, 0.1), frames.total, 32, e.Seed) // Start with enough for length
+	if err != nil {
+		return nil, err
 	}
 
 	// Extract length first
 	var lengthBytes [4]byte
 	for i := 0; i < 32; i++ {
-		bit := videoData[indices[i]] & 1
+		bit := fileData[frames.offset(indices[i])] & 1
 		byteIndex := i / 8
 		bitPosition := 7 - (i % 8)
 		lengthBytes[byteIndex] |= bit << bitPosition
 	}
 
 	dataLength := binary.BigEndian.Uint32(lengthBytes[:])
-	if dataLength > uint32(len(videoData)/8) {
-		return "", errors.New("invalid data length")
+	if dataLength > uint32((frames.total-32)/8) {
+		return nil, errors.New("invalid data length")
 	}
 
 	// Generate indices for the full message
-	indices = generatePixelOrderVid(len(videoData), int(dataLength)*8+32, e.Seed)
-
-	// Validate all indices
-	for _, idx := range indices {
-		if idx >= len(videoData) {
-			return "", errors.New("pixel index out of bounds during data extraction")
-		}
+	indices, err = generatePixelOrderVid(t.span(0.1, 0.9), frames.total, int(dataLength)*8+32, e.Seed)
+	if err != nil {
+		return nil, err
 	}
 
 	// Extract data
 	extractedData := make([]byte, dataLength)
+	if err := t.phase(0.9, 1, int(dataLength)); err != nil {
+		return nil, err
+	}
 	for i := 0; i < int(dataLength); i++ {
+		if err := t.advance(1); err != nil {
+			return nil, err
+		}
 		for b := 0; b < 8; b++ {
 			bitIndex := 32 + i*8 + b
-			bit := videoData[indices[bitIndex]] & 1
+			bit := fileData[frames.offset(indices[bitIndex])] & 1
 			extractedData[i] |= bit << (7 - b)
 		}
 	}
 
-	return string(extractedData), nil
+	return extractedData, t.report(1)
 }
 
-// Helper functions
-
-// findMoviChunk locates the movi chunk in AVI data and returns its offset and length
-func findMoviChunk(fileData []byte) (int, int, error) {
-	fileSize := len(fileData)
-	offset := 12 // Start after RIFF/AVI header
-
-	for offset < fileSize-8 {
-		// Read chunk ID and size
-		chunkID := string(fileData[offset : offset+4])
-		chunkSize := int(binary.LittleEndian.Uint32(fileData[offset+4 : offset+8]))
-
-		// Make chunkSize even if it's odd (AVI padding rule)
-		if chunkSize%2 == 1 {
-			chunkSize++
-		}
-
-		// Check if this is LIST chunk
-		if chunkID == "LIST" && offset+12 <= fileSize {
-			// Check if this LIST contains movi
-			listType := string(fileData[offset+8 : offset+12])
-			if listType == "movi" {
-				// Found movi chunk
-				dataOffset := offset + 12   // Skip LIST header and type
-				dataLength := chunkSize - 4 // Subtract the size of the LIST type
-
-				// Ensure we don't exceed the file bounds
-				if dataOffset+dataLength > fileSize {
-					dataLength = fileSize - dataOffset
-				}
-
-				return dataOffset, dataLength, nil
-			}
-		}
-
-		// Move to next chunk
-		offset += 8 + chunkSize
+// EncodeMessage is a convenience method that encodes a text message
+func (e *VideoEncoder) EncodeMessage(inputPath, outputPath, message string) error {
+	return e.EncodeData(inputPath, outputPath, []byte(message))
+}
 
-		// Safety check
-		if offset >= fileSize {
-			break
-		}
+// DecodeMessage is a convenience method that decodes a text message
+func (e *VideoEncoder) DecodeMessage(inputPath string) (string, error) {
+	data, err := e.DecodeData(inputPath)
+	if err != nil {
+		return "", err
 	}
-
-	return 0, 0, errors.New("movi chunk not found")
+	return string(data), nil
 }
 
+// Helper functions
+
 // generatePixelOrderVid creates a deterministic order of pixel indices
-func generatePixelOrderVid(totalPixels, requiredBits int, seed int64) []int {
+func generatePixelOrderVid(t *tracker, totalPixels, requiredBits int, seed int64) ([]int, error) {
 	indices := make([]int, requiredBits)
 
 	if seed < 0 {
@@ -226,21 +217,27 @@ func generatePixelOrderVid(totalPixels, requiredBits int, seed int64) []int {
 		for i := 0; i < requiredBits; i++ {
 			indices[i] = i % totalPixels
 		}
-		return indices
+		return indices, nil
 	}
 
 	// Random mode with seed
 	rng := rand.New(rand.NewSource(seed))
 	used := make(map[int]bool)
 
+	if err := t.phase(0, 1, requiredBits); err != nil {
+		return nil, err
+	}
 	for i := 0; i < requiredBits; {
 		idx := rng.Intn(totalPixels)
 		if !used[idx] {
 			used[idx] = true
 			indices[i] = idx
 			i++
+			if err := t.advance(1); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	return indices
+	return indices, nil
 }
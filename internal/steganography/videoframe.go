@@ -0,0 +1,516 @@
+// videoframe.go - Per-frame image steganography for uncompressed AVI and Y4M video
+package steganography
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"os"
+)
+
+// frameSegmentHeaderSize is the size of the header stored with each frame segment:
+// [4 bytes payload CRC32][4 bytes segment index][4 bytes segment count]
+// [4 bytes payload length][4 bytes segment CRC32]
+const frameSegmentHeaderSize = 20
+
+// VideoFrameEncoder hides data in uncompressed AVI or Y4M frames by decoding each frame
+// into an image and applying the LSB or BPCS image encoder to it. The payload is
+// split into segments that are spread over the frames in a keyed order, and each
+// segment carries its own header so surviving frames can be recovered on their own.
+type VideoFrameEncoder struct {
+	Seed                int64
+	Method              string  // Image method applied per frame: "lsb" or "bpcs"
+	ComplexityThreshold float64 // Threshold for the BPCS method
+}
+
+// FrameRecovery is the result of extracting a payload from the frames of a video
+type FrameRecovery struct {
+	Data            []byte // Payload with missing segments zero-filled
+	SegmentCount    int    // Number of segments the payload was split into
+	MissingSegments []int  // Indices of the segments that were not found
+}
+
+// NewVideoFrameEncoder creates a new per-frame video encoder with the given seed and image method
+func NewVideoFrameEncoder(seed string, method string, complexityThreshold float64) (*VideoFrameEncoder, error) {
+	if method == "" {
+		method = "lsb"
+	}
+	if method != "lsb" && method != "bpcs" {
+		return nil, errors.New("unknown frame method: " + method)
+	}
+
+	// Validate complexity threshold
+	if complexityThreshold < 0.3 || complexityThreshold >= 0.5 {
+		complexityThreshold = 0.45 // Default value if out of range
+	}
+
+	return &VideoFrameEncoder{
+		Seed:                parseSeed(seed),
+		Method:              method,
+		ComplexityThreshold: complexityThreshold,
+	}, nil
+}
+
+// imageEncoder returns the image encoder applied to each frame
+func (e *VideoFrameEncoder) imageEncoder() imageEmbedder {
+	if e.Method == "bpcs" {
+		return &BPCSEncoder{Seed: e.Seed, ComplexityThreshold: e.ComplexityThreshold}
+	}
+	return &LSBEncoder{Seed: e.Seed}
+}
+
+// EncodeData embeds binary data into the frames of an uncompressed AVI or Y4M file
+func (e *VideoFrameEncoder) EncodeData(inputPath, outputPath string, data []byte) error {
+	return e.EncodeDataContext(context.Background(), inputPath, outputPath, data, nil)
+}
+
+// EncodeDataContext is EncodeData with cancellation and progress reporting
+// Each frame's share of the progress is reported by the image encoder.
+func (e *VideoFrameEncoder) EncodeDataContext(ctx context.Context, inputPath, outputPath string, data []byte, progress Progress) error {
+	t := newTracker(ctx, progress)
+
+	// Read the video and locate its frames
+	frames, err := openVideoFrames(inputPath)
+	if err != nil {
+		return err
+	}
+	if err := frames.validate(); err != nil {
+		return err
+	}
+	frameCount := frames.count()
+
+	// Split the payload into one segment per frame at most
+	segmentCount := frameCount
+	if len(data) < segmentCount {
+		segmentCount = max(len(data), 1)
+	}
+	segmentSize := (len(data) + segmentCount - 1) / segmentCount
+	if segmentSize > 0 {
+		segmentCount = (len(data) + segmentSize - 1) / segmentSize
+	}
+	payloadCRC := crc32.ChecksumIEEE(data)
+
+	// Use seed to determine frame order
+	rng := NewSeededRNG(e.Seed)
+	order := rng.Perm(frameCount)
+
+	encoder := e.imageEncoder()
+	for i := 0; i < segmentCount; i++ {
+		frameTracker := t.span(0.05+0.9*float64(i)/float64(segmentCount), 0.05+0.9*float64(i+1)/float64(segmentCount))
+		start := min(i*segmentSize, len(data))
+		end := min(start+segmentSize, len(data))
+
+		// Build the segment with its header
+		segment := make([]byte, frameSegmentHeaderSize+end-start)
+		binary.BigEndian.PutUint32(segment[0:4], payloadCRC)
+		binary.BigEndian.PutUint32(segment[4:8], uint32(i))
+		binary.BigEndian.PutUint32(segment[8:12], uint32(segmentCount))
+		binary.BigEndian.PutUint32(segment[12:16], uint32(len(data)))
+		copy(segment[frameSegmentHeaderSize:], data[start:end])
+		binary.BigEndian.PutUint32(segment[16:20], segmentChecksum(segment))
+
+		// Embed the segment into its frame
+		img, err := frames.frame(order[i])
+		if err != nil {
+			return err
+		}
+		if err := encoder.embedInImage(frameTracker, img, segment); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return fmt.Errorf("frame %d: %v", order[i], err)
+		}
+		frames.setFrame(order[i], img)
+	}
+
+	// Write the modified file
+	if err := frames.write(outputPath); err != nil {
+		return err
+	}
+	return t.report(1)
+}
+
+// Capacity returns the largest payload in bytes that EncodeData is guaranteed to hide
+// in the video. A full payload puts an equal segment in every frame, so the smallest
+// frame capacity after the segment header limits every frame.
+func (e *VideoFrameEncoder) Capacity(inputPath string) (int, error) {
+	// Read the video and locate its frames
+	frames, err := openVideoFrames(inputPath)
+	if err != nil {
+		return 0, err
+	}
+
+	encoder := e.imageEncoder()
+	frameCapacity := -1
+	for i := 0; i < frames.count(); i++ {
+		img, err := frames.frame(i)
+		if err != nil {
+			return 0, err
+		}
+
+		capacity := encoder.imageCapacity(img)
+		if frameCapacity < 0 || capacity < frameCapacity {
+			frameCapacity = capacity
+		}
+	}
+
+	return max(frameCapacity-frameSegmentHeaderSize, 0) * frames.count(), nil
+}
+
+// DecodeData extracts hidden binary data from the frames of a video
+// It fails if any segment is missing; use DecodePartial to recover what is left
+func (e *VideoFrameEncoder) DecodeData(inputPath string) ([]byte, error) {
+	return e.DecodeDataContext(context.Background(), inputPath, nil)
+}
+
+// DecodeDataContext is DecodeData with cancellation and progress reporting
+func (e *VideoFrameEncoder) DecodeDataContext(ctx context.Context, inputPath string, progress Progress) ([]byte, error) {
+	recovery, err := e.DecodePartialContext(ctx, inputPath, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(recovery.MissingSegments) > 0 {
+		return nil, fmt.Errorf("missing %d of %d payload segments", len(recovery.MissingSegments), recovery.SegmentCount)
+	}
+
+	return recovery.Data, nil
+}
+
+// DecodePartial extracts every payload segment that survives in the frames of a
+// video, in any frame order, and reports which segments are missing
+func (e *VideoFrameEncoder) DecodePartial(inputPath string) (*FrameRecovery, error) {
+	return e.DecodePartialContext(context.Background(), inputPath, nil)
+}
+
+// DecodePartialContext is DecodePartial with cancellation and progress reporting
+func (e *VideoFrameEncoder) DecodePartialContext(ctx context.Context, inputPath string, progress Progress) (*FrameRecovery, error) {
+	t := newTracker(ctx, progress)
+
+	// Read the video and locate its frames
+	frames, err := openVideoFrames(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	frameCount := frames.count()
+
+	// Scan every frame for a valid segment
+	encoder := e.imageEncoder()
+	segments := make(map[uint32][]byte)
+	var payloadCRC, segmentCount, payloadLength uint32
+	for i := 0; i < frameCount; i++ {
+		// Frames that fail to decode are skipped, so check for cancellation first
+		frameTracker := t.span(0.05+0.9*float64(i)/float64(frameCount), 0.05+0.9*float64(i+1)/float64(frameCount))
+		if err := frameTracker.report(0); err != nil {
+			return nil, err
+		}
+
+		img, err := frames.frame(i)
+		if err != nil {
+			continue
+		}
+		segment, err := encoder.extractFromImage(frameTracker, img)
+		if err := t.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err != nil || len(segment) < frameSegmentHeaderSize {
+			continue
+		}
+		if binary.BigEndian.Uint32(segment[16:20]) != segmentChecksum(segment) {
+			continue
+		}
+
+		// Skip headers that could not have been written to this video
+		count := binary.BigEndian.Uint32(segment[8:12])
+		length := binary.BigEndian.Uint32(segment[12:16])
+		index := binary.BigEndian.Uint32(segment[4:8])
+		if !validFrameSegment(index, count, length, len(segment)-frameSegmentHeaderSize, frameCount) {
+			continue
+		}
+
+		// The first valid segment fixes the payload the others must belong to
+		if len(segments) == 0 {
+			payloadCRC = binary.BigEndian.Uint32(segment[0:4])
+			segmentCount = count
+			payloadLength = length
+		} else if binary.BigEndian.Uint32(segment[0:4]) != payloadCRC || count != segmentCount || length != payloadLength {
+			continue
+		}
+
+		segments[index] = segment[frameSegmentHeaderSize:]
+	}
+
+	if len(segments) == 0 {
+		return nil, errors.New("no payload segments found")
+	}
+
+	// Reassemble the payload, zero-filling missing segments
+	segmentSize := (int(payloadLength) + int(segmentCount) - 1) / int(segmentCount)
+	recovery := &FrameRecovery{
+		Data:            make([]byte, payloadLength),
+		SegmentCount:    int(segmentCount),
+		MissingSegments: []int{},
+	}
+	for i := uint32(0); i < segmentCount; i++ {
+		segment, ok := segments[i]
+		if !ok {
+			recovery.MissingSegments = append(recovery.MissingSegments, int(i))
+			continue
+		}
+		copy(recovery.Data[min(int(i)*segmentSize, len(recovery.Data)):], segment)
+	}
+
+	if len(recovery.MissingSegments) == 0 && crc32.ChecksumIEEE(recovery.Data) != payloadCRC {
+		return nil, errors.New("payload checksum mismatch")
+	}
+
+	return recovery, t.report(1)
+}
+
+// EncodeMessage is a convenience method that encodes a text message
+func (e *VideoFrameEncoder) EncodeMessage(inputPath, outputPath, message string) error {
+	return e.EncodeData(inputPath, outputPath, []byte(message))
+}
+
+// DecodeMessage is a convenience method that decodes a text message
+func (e *VideoFrameEncoder) DecodeMessage(inputPath string) (string, error) {
+	data, err := e.DecodeData(inputPath)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Helper functions
+
+// validFrameSegment reports whether a segment header fits a video with frameCount frames:
+// the segment count is at most one per frame and the segment holds exactly its share
+// of the payload, which bounds the payload length by the data actually stored
+func validFrameSegment(index, count, length uint32, size, frameCount int) bool {
+	if count == 0 || int64(count) > int64(frameCount) || index >= count {
+		return false
+	}
+	if length == 0 {
+		return count == 1 && size == 0 // Empty payloads have a single empty segment
+	}
+	segmentSize := (int64(length) + int64(count) - 1) / int64(count)
+	expected := min(segmentSize, int64(length)-int64(index)*segmentSize)
+	return expected > 0 && int64(size) == expected
+}
+
+// segmentChecksum computes the CRC32 of a frame segment with its checksum field zeroed
+func segmentChecksum(segment []byte) uint32 {
+	h := crc32.NewIEEE()
+	h.Write(segment[0:16])
+	h.Write([]byte{0, 0, 0, 0})
+	h.Write(segment[frameSegmentHeaderSize:])
+	return h.Sum32()
+}
+
+// videoFrames gives uniform access to the frames of an AVI or Y4M video as images
+type videoFrames interface {
+	count() int
+	frame(i int) (*image.RGBA, error) // Decodes frame i
+	setFrame(i int, img *image.RGBA)  // Stores an image decoded by frame back into frame i
+	validate() error                  // Checks that the video can be written back intact
+	write(outputPath string) error
+}
+
+// openVideoFrames reads an AVI or Y4M file, told apart by its signature
+func openVideoFrames(inputPath string) (videoFrames, error) {
+	fileData, err := os.ReadFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(fileData, []byte("YUV4MPEG2 ")) {
+		video, err := readY4M(bytes.NewReader(fileData))
+		if err != nil {
+			return nil, err
+		}
+		if video.SampleSize != 1 {
+			return nil, errors.New("per-frame embedding requires 8-bit Y4M video")
+		}
+		if video.Width < 3 {
+			return nil, errors.New("Y4M frames are too narrow for per-frame embedding")
+		}
+		return &y4mFrames{video: video}, nil
+	}
+
+	avi, err := parseAVI(fileData)
+	if err != nil {
+		return nil, err
+	}
+	frames := &aviFrames{fileData: fileData, avi: avi, chunks: avi.frameChunks()}
+	if len(frames.chunks) == 0 {
+		return nil, errors.New("no uncompressed video frames found")
+	}
+	return frames, nil
+}
+
+// aviFrames holds the uncompressed DIB frames of an AVI file
+type aviFrames struct {
+	fileData []byte
+	avi      *aviFile
+	chunks   []aviChunk
+}
+
+func (f *aviFrames) count() int {
+	return len(f.chunks)
+}
+
+func (f *aviFrames) frame(i int) (*image.RGBA, error) {
+	stream, err := f.avi.frameStream(f.chunks[i])
+	if err != nil {
+		return nil, err
+	}
+	return decodeDIBFrame(f.fileData[f.chunks[i].Offset:f.chunks[i].Offset+f.chunks[i].Size], stream)
+}
+
+func (f *aviFrames) setFrame(i int, img *image.RGBA) {
+	stream, _ := f.avi.frameStream(f.chunks[i])
+	encodeDIBFrame(img, f.fileData[f.chunks[i].Offset:f.chunks[i].Offset+f.chunks[i].Size], stream)
+}
+
+// validate checks the input; only frame pixels change, so a sound input gives a playable output
+func (f *aviFrames) validate() error {
+	if err := f.avi.validate(f.fileData); err != nil {
+		return errors.New("input video failed AVI validation: " + err.Error())
+	}
+	return nil
+}
+
+func (f *aviFrames) write(outputPath string) error {
+	return os.WriteFile(outputPath, f.fileData, 0644)
+}
+
+// y4mFrames holds the frames of an 8-bit Y4M video. Each frame's luma plane is
+// packed three samples to a pixel into the R, G and B channels, so every channel
+// the image encoders touch maps to exactly one luma sample. Chroma planes and the
+// last width%3 columns are left unchanged.
+type y4mFrames struct {
+	video *y4mVideo
+}
+
+func (f *y4mFrames) count() int {
+	return len(f.video.Frames)
+}
+
+func (f *y4mFrames) frame(i int) (*image.RGBA, error) {
+	width, height := f.video.Width/3, f.video.Height
+	luma := f.video.Frames[i].Data
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			src := y*f.video.Width + x*3
+			dst := img.PixOffset(x, y)
+			copy(img.Pix[dst:dst+3], luma[src:src+3])
+			img.Pix[dst+3] = 0xFF
+		}
+	}
+	return img, nil
+}
+
+func (f *y4mFrames) setFrame(i int, img *image.RGBA) {
+	width, height := f.video.Width/3, f.video.Height
+	luma := f.video.Frames[i].Data
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dst := y*f.video.Width + x*3
+			src := img.PixOffset(x, y)
+			copy(luma[dst:dst+3], img.Pix[src:src+3])
+		}
+	}
+}
+
+func (f *y4mFrames) validate() error {
+	return nil
+}
+
+func (f *y4mFrames) write(outputPath string) error {
+	return writeY4MFile(outputPath, f.video)
+}
+
+// frameStream returns the video stream a frame chunk belongs to
+func (a *aviFile) frameStream(frame aviChunk) (aviStream, error) {
+	index, ok := streamIndex(frame.ID)
+	if !ok || index >= len(a.Streams) || a.Streams[index].Type != "vids" {
+		return aviStream{}, errors.New("frame chunk " + frame.ID + " has no video stream header")
+	}
+	return a.Streams[index], nil
+}
+
+// decodeDIBFrame converts an uncompressed 24 or 32-bit DIB frame to an RGBA image
+func decodeDIBFrame(pixels []byte, stream aviStream) (*image.RGBA, error) {
+	width, height, stride, bottomUp, err := dibLayout(stream)
+	if err != nil {
+		return nil, err
+	}
+	if len(pixels) < stride*height {
+		return nil, errors.New("frame is smaller than its stream header describes")
+	}
+
+	bytesPerPixel := stream.BitCount / 8
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := y
+		if bottomUp {
+			row = height - 1 - y
+		}
+		for x := 0; x < width; x++ {
+			src := row*stride + x*bytesPerPixel
+			dst := img.PixOffset(x, y)
+			// DIB pixels are stored as BGR(A)
+			img.Pix[dst] = pixels[src+2]
+			img.Pix[dst+1] = pixels[src+1]
+			img.Pix[dst+2] = pixels[src]
+			img.Pix[dst+3] = 0xFF
+		}
+	}
+
+	return img, nil
+}
+
+// encodeDIBFrame writes the colour channels of an RGBA image back into a DIB frame
+func encodeDIBFrame(img *image.RGBA, pixels []byte, stream aviStream) {
+	width, height, stride, bottomUp, _ := dibLayout(stream)
+	bytesPerPixel := stream.BitCount / 8
+
+	for y := 0; y < height; y++ {
+		row := y
+		if bottomUp {
+			row = height - 1 - y
+		}
+		for x := 0; x < width; x++ {
+			dst := row*stride + x*bytesPerPixel
+			src := img.PixOffset(x, y)
+			pixels[dst+2] = img.Pix[src]
+			pixels[dst+1] = img.Pix[src+1]
+			pixels[dst] = img.Pix[src+2]
+		}
+	}
+}
+
+// dibLayout returns the dimensions, row stride and row order of a stream's DIB frames
+func dibLayout(stream aviStream) (width, height, stride int, bottomUp bool, err error) {
+	if stream.BitCount != 24 && stream.BitCount != 32 {
+		return 0, 0, 0, false, fmt.Errorf("unsupported frame bit depth: %d", stream.BitCount)
+	}
+
+	width, height = stream.Width, stream.Height
+	bottomUp = height > 0 // Positive heights are stored bottom row first
+	if height < 0 {
+		height = -height
+	}
+	if width <= 0 || height == 0 {
+		return 0, 0, 0, false, errors.New("invalid frame dimensions")
+	}
+
+	// Rows are padded to a multiple of 4 bytes
+	stride = (width*stream.BitCount/8 + 3) &^ 3
+	return width, height, stride, bottomUp, nil
+}
@@ -0,0 +1,143 @@
+// visualize.go - Bit-plane and BPCS complexity renderings for visual attacks
+package steganography
+
+import (
+	"errors"
+	"image"
+	"image/color"
+)
+
+// RenderBitPlane renders one bit plane of an image. For a single channel ("red",
+// "green" or "blue") set bits are white and clear bits black; for "all" each
+// channel of the output shows the plane of the same input channel.
+func RenderBitPlane(img image.Image, channel string, plane int) (image.Image, error) {
+	if plane < 0 || plane > 7 {
+		return nil, errors.New("bit plane must be between 0 and 7")
+	}
+	channelIndex, err := parseChannel(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	rgbaImg := toRGBA(img)
+	bounds := rgbaImg.Bounds()
+	output := image.NewRGBA(bounds)
+
+	// Render the image block by block with the BPCS plane extraction
+	for startY := 0; startY < bounds.Max.Y; startY += 8 {
+		for startX := 0; startX < bounds.Max.X; startX += 8 {
+			var blocks [3]Block
+			for c := 0; c < 3; c++ {
+				blocks[c] = extractBitPlaneBlock(rgbaImg, startX, startY, plane, c)
+			}
+
+			for y := 0; y < 8 && startY+y < bounds.Max.Y; y++ {
+				for x := 0; x < 8 && startX+x < bounds.Max.X; x++ {
+					var pixel color.RGBA
+					if channelIndex < 0 {
+						pixel = color.RGBA{bitColor(blocks[0][y][x]), bitColor(blocks[1][y][x]), bitColor(blocks[2][y][x]), 0xFF}
+					} else {
+						v := bitColor(blocks[channelIndex][y][x])
+						pixel = color.RGBA{v, v, v, 0xFF}
+					}
+					output.SetRGBA(startX+x, startY+y, pixel)
+				}
+			}
+		}
+	}
+
+	return output, nil
+}
+
+// RenderComplexityMap renders a heatmap of the BPCS complexity of each 8x8 block
+// in one bit plane of a channel, from blue (flat) to red (noisy). Blocks that
+// BPCSEncoder would not use at the given threshold are dimmed.
+func RenderComplexityMap(img image.Image, channel string, plane int, threshold float64) (image.Image, error) {
+	if plane < 0 || plane > 7 {
+		return nil, errors.New("bit plane must be between 0 and 7")
+	}
+	channelIndex, err := parseChannel(channel)
+	if err != nil {
+		return nil, err
+	}
+	if channelIndex < 0 {
+		return nil, errors.New("complexity maps need a single channel")
+	}
+
+	rgbaImg := toRGBA(img)
+	bounds := rgbaImg.Bounds()
+	output := image.NewRGBA(bounds)
+
+	for startY := 0; startY < bounds.Max.Y; startY += 8 {
+		for startX := 0; startX < bounds.Max.X; startX += 8 {
+			complexity := calculateComplexity(extractBitPlaneBlock(rgbaImg, startX, startY, plane, channelIndex))
+			pixel := heatColor(complexity)
+
+			// Partial blocks at the edges and simple blocks are never used for data
+			usable := startX+8 <= bounds.Max.X && startY+8 <= bounds.Max.Y && complexity > threshold
+			if !usable {
+				pixel.R /= 3
+				pixel.G /= 3
+				pixel.B /= 3
+			}
+
+			for y := 0; y < 8 && startY+y < bounds.Max.Y; y++ {
+				for x := 0; x < 8 && startX+x < bounds.Max.X; x++ {
+					output.SetRGBA(startX+x, startY+y, pixel)
+				}
+			}
+		}
+	}
+
+	return output, nil
+}
+
+// Helper functions
+
+// parseChannel returns the index of a channel name, or -1 for "all" or empty
+func parseChannel(channel string) (int, error) {
+	if channel == "" || channel == "all" {
+		return -1, nil
+	}
+	for i, name := range channelNames {
+		if channel == name {
+			return i, nil
+		}
+	}
+	return 0, errors.New("unknown channel: " + channel)
+}
+
+// toRGBA copies an image into an RGBA image whose bounds start at the origin
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgbaImg := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			rgbaImg.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return rgbaImg
+}
+
+// bitColor maps a bit to black or white
+func bitColor(bit bool) uint8 {
+	if bit {
+		return 0xFF
+	}
+	return 0
+}
+
+// heatColor maps a value from 0 to 1 onto a blue-green-red colour ramp
+func heatColor(value float64) color.RGBA {
+	value = max(0, min(1, value))
+	green := 1 - 2*value
+	if green < 0 {
+		green = -green
+	}
+	return color.RGBA{
+		R: uint8(255 * value),
+		G: uint8(255 * (1 - green)),
+		B: uint8(255 * (1 - value)),
+		A: 0xFF,
+	}
+}
@@ -0,0 +1,392 @@
+// watermark.go - Robust DCT watermarking that survives recompression and resizing
+package steganography
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"strconv"
+)
+
+// Watermark domain parameters. The luma is resampled to a fixed square domain, so
+// a watermark read back after uniform resizing lands on the same blocks.
+const (
+	watermarkDomain       = 256 // Domain width and height in pixels
+	watermarkBlock        = 8   // DCT block size
+	watermarkMaxIDBytes   = 32  // Longest ID (256 bits)
+	watermarkMinIDBytes   = 1
+	watermarkDefaultDelta = 28.0 // Default QIM step on the orthonormal DCT coefficients
+)
+
+// watermarkMinSize is the smallest image side: twice the domain, so the image
+// still covers the domain after a 50% downscale
+const watermarkMinSize = 2 * watermarkDomain
+
+// watermarkFrameSize is the size of the frame repeated over the image:
+// [1 byte ID length][32 bytes ID, zero padded][4 bytes CRC32 of the length and ID]
+const watermarkFrameSize = 1 + watermarkMaxIDBytes + 4
+
+// watermarkCoefficients are the mid-band DCT coefficients that carry one bit each.
+// They are low enough in the domain to pass JPEG quantization at the original scale.
+var watermarkCoefficients = [][2]int{{1, 2}, {2, 1}, {2, 2}}
+
+// dctBasis holds the orthonormal 8-point DCT-II basis: dctBasis[u][x]
+var dctBasis = func() [watermarkBlock][watermarkBlock]float64 {
+	var basis [watermarkBlock][watermarkBlock]float64
+	for u := 0; u < watermarkBlock; u++ {
+		scale := math.Sqrt(2.0 / watermarkBlock)
+		if u == 0 {
+			scale = math.Sqrt(1.0 / watermarkBlock)
+		}
+		for x := 0; x < watermarkBlock; x++ {
+			basis[u][x] = scale * math.Cos(float64(2*x+1)*float64(u)*math.Pi/(2*watermarkBlock))
+		}
+	}
+	return basis
+}()
+
+// WatermarkEncoder embeds a short ID into the mid-band DCT coefficients of an
+// image's luma using quantization index modulation. The ID is protected by a
+// CRC32 and repeated over every coefficient slot in a keyed order, and the
+// repetitions are combined by soft voting when it is read back. Images must be at
+// least twice the domain size on each side, which keeps the domain at or below the
+// resolution of a 50% downscale, so the watermark survives it together with JPEG.
+type WatermarkEncoder struct {
+	Seed     int64
+	Strength float64 // QIM step; larger survives stronger attacks but is more visible
+}
+
+// NewWatermarkEncoder creates a new robust watermark encoder with the given seed and strength
+func NewWatermarkEncoder(seed string, strength float64) (*WatermarkEncoder, error) {
+	// Validate strength
+	if strength <= 0 {
+		strength = watermarkDefaultDelta // Default value
+	}
+	if strength > 100 {
+		return nil, errors.New("strength must be at most 100")
+	}
+
+	return &WatermarkEncoder{
+		Seed:     parseSeed(seed),
+		Strength: strength,
+	}, nil
+}
+
+// Embed writes an image with the watermark ID embedded
+func (e *WatermarkEncoder) Embed(inputPath, outputPath string, id []byte) error {
+	return e.EmbedContext(context.Background(), inputPath, outputPath, id, nil)
+}
+
+// EmbedContext is Embed with cancellation and progress reporting
+func (e *WatermarkEncoder) EmbedContext(ctx context.Context, inputPath, outputPath string, id []byte, progress Progress) error {
+	img, err := decodeImageFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	marked, err := e.EmbedImageContext(ctx, img, id, progress)
+	if err != nil {
+		return err
+	}
+
+	// Save the output image
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	return png.Encode(outFile, marked)
+}
+
+// Extract reads the watermark ID from an image file
+func (e *WatermarkEncoder) Extract(inputPath string) ([]byte, error) {
+	return e.ExtractContext(context.Background(), inputPath, nil)
+}
+
+// ExtractContext is Extract with cancellation and progress reporting
+func (e *WatermarkEncoder) ExtractContext(ctx context.Context, inputPath string, progress Progress) ([]byte, error) {
+	img, err := decodeImageFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	return e.ExtractImageContext(ctx, img, progress)
+}
+
+// EmbedImage returns a copy of an image with the watermark ID embedded
+func (e *WatermarkEncoder) EmbedImage(img image.Image, id []byte) (*image.RGBA, error) {
+	return e.EmbedImageContext(context.Background(), img, id, nil)
+}
+
+// EmbedImageContext is EmbedImage with cancellation and progress reporting
+func (e *WatermarkEncoder) EmbedImageContext(ctx context.Context, img image.Image, id []byte, progress Progress) (*image.RGBA, error) {
+	return e.embedImage(newTracker(ctx, progress), img, id)
+}
+
+// ExtractImage reads the watermark ID from an image
+func (e *WatermarkEncoder) ExtractImage(img image.Image) ([]byte, error) {
+	return e.ExtractImageContext(context.Background(), img, nil)
+}
+
+// ExtractImageContext is ExtractImage with cancellation and progress reporting
+func (e *WatermarkEncoder) ExtractImageContext(ctx context.Context, img image.Image, progress Progress) ([]byte, error) {
+	t := newTracker(ctx, progress)
+	frame, _, err := e.readFrame(t, img)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := frameID(frame)
+	if err != nil {
+		return nil, err
+	}
+	return id, t.report(1)
+}
+
+// Helper functions
+
+// embedImage returns a copy of an image with the watermark ID embedded
+func (e *WatermarkEncoder) embedImage(t *tracker, img image.Image, id []byte) (*image.RGBA, error) {
+	if len(id) < watermarkMinIDBytes || len(id) > watermarkMaxIDBytes {
+		return nil, errors.New("watermark ID must be between 1 and 32 bytes")
+	}
+
+	rgbaImg := toRGBA(img)
+	width, height := rgbaImg.Bounds().Dx(), rgbaImg.Bounds().Dy()
+	if width < watermarkMinSize || height < watermarkMinSize {
+		return nil, errors.New("image must be at least " + strconv.Itoa(watermarkMinSize) + "x" + strconv.Itoa(watermarkMinSize) + " pixels to watermark")
+	}
+
+	// Build the repeated frame bits
+	frame := watermarkFrame(id)
+	slots := e.slotOrder()
+
+	// Quantize the coefficients of the domain luma
+	if err := t.phase(0, 0.4, 0); err != nil {
+		return nil, err
+	}
+	domain := resampleGray(imageLuma(rgbaImg), width, height, watermarkDomain, watermarkDomain)
+	delta := make([]float64, len(domain))
+	frameBits := watermarkFrameSize * 8
+	if err := t.phase(0.4, 0.5, len(slots)); err != nil {
+		return nil, err
+	}
+	for k, slot := range slots {
+		i := k % frameBits
+		bit := (frame[i/8] >> (7 - i%8)) & 1
+		bx, by, u, v := slotPosition(slot)
+
+		coefficient := blockCoefficient(domain, bx, by, u, v)
+		target := qimQuantize(coefficient, bit, e.Strength)
+		addBasis(delta, bx, by, u, v, target-coefficient)
+		if err := t.advance(1); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the domain change to the full resolution luma
+	fullDelta := resampleGray(delta, watermarkDomain, watermarkDomain, width, height)
+	output := image.NewRGBA(rgbaImg.Bounds())
+	copy(output.Pix, rgbaImg.Pix)
+	if err := t.phase(0.5, 1, len(fullDelta)); err != nil {
+		return nil, err
+	}
+	for i, d := range fullDelta {
+		offset := i * 4
+		for c := 0; c < 3; c++ {
+			value := math.Round(float64(output.Pix[offset+c]) + d)
+			output.Pix[offset+c] = uint8(math.Max(0, math.Min(255, value)))
+		}
+		if err := t.advance(1); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, t.report(1)
+}
+
+// frameID checks a recovered frame and returns its ID
+func frameID(frame []byte) ([]byte, error) {
+	length := int(frame[0])
+	if length < watermarkMinIDBytes || length > watermarkMaxIDBytes {
+		return nil, errors.New("no watermark found")
+	}
+	checksum := binary.BigEndian.Uint32(frame[1+watermarkMaxIDBytes:])
+	if checksum != crc32.ChecksumIEEE(frame[:1+watermarkMaxIDBytes]) {
+		return nil, errors.New("no watermark found")
+	}
+
+	return append([]byte{}, frame[1:1+length]...), nil
+}
+
+// readFrame recovers the frame by soft voting over its repetitions and also
+// returns the hard decision of every slot, for measuring raw bit errors
+func (e *WatermarkEncoder) readFrame(t *tracker, img image.Image) ([]byte, []byte, error) {
+	if err := t.phase(0, 0.8, 0); err != nil {
+		return nil, nil, err
+	}
+	rgbaImg := toRGBA(img)
+	width, height := rgbaImg.Bounds().Dx(), rgbaImg.Bounds().Dy()
+	domain := resampleGray(imageLuma(rgbaImg), width, height, watermarkDomain, watermarkDomain)
+
+	frameBits := watermarkFrameSize * 8
+	votes := make([]float64, frameBits)
+	slots := e.slotOrder()
+	raw := make([]byte, len(slots))
+	if err := t.phase(0.8, 1, len(slots)); err != nil {
+		return nil, nil, err
+	}
+	for k, slot := range slots {
+		bx, by, u, v := slotPosition(slot)
+		vote := qimVote(blockCoefficient(domain, bx, by, u, v), e.Strength)
+		votes[k%frameBits] += vote
+		if vote > 0 {
+			raw[k] = 1
+		}
+		if err := t.advance(1); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	frame := make([]byte, watermarkFrameSize)
+	for i, vote := range votes {
+		if vote > 0 {
+			frame[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return frame, raw, nil
+}
+
+// slotOrder returns the keyed order of the coefficient slots used for the
+// repeated frame, trimmed to a whole number of repetitions
+func (e *WatermarkEncoder) slotOrder() []int {
+	blocks := (watermarkDomain / watermarkBlock) * (watermarkDomain / watermarkBlock)
+	total := blocks * len(watermarkCoefficients)
+	frameBits := watermarkFrameSize * 8
+
+	rng := NewSeededRNG(e.Seed)
+	return rng.Perm(total)[:total/frameBits*frameBits]
+}
+
+// slotPosition maps a slot to its block and DCT coefficient
+func slotPosition(slot int) (bx, by, u, v int) {
+	blocksPerRow := watermarkDomain / watermarkBlock
+	block := slot / len(watermarkCoefficients)
+	coefficient := watermarkCoefficients[slot%len(watermarkCoefficients)]
+	return block % blocksPerRow, block / blocksPerRow, coefficient[0], coefficient[1]
+}
+
+// watermarkFrame builds the frame for an ID
+func watermarkFrame(id []byte) []byte {
+	frame := make([]byte, watermarkFrameSize)
+	frame[0] = byte(len(id))
+	copy(frame[1:], id)
+	binary.BigEndian.PutUint32(frame[1+watermarkMaxIDBytes:], crc32.ChecksumIEEE(frame[:1+watermarkMaxIDBytes]))
+	return frame
+}
+
+// blockCoefficient projects a domain block onto the (u, v) DCT basis function
+func blockCoefficient(domain []float64, bx, by, u, v int) float64 {
+	sum := 0.0
+	for y := 0; y < watermarkBlock; y++ {
+		row := (by*watermarkBlock + y) * watermarkDomain
+		for x := 0; x < watermarkBlock; x++ {
+			sum += domain[row+bx*watermarkBlock+x] * dctBasis[u][x] * dctBasis[v][y]
+		}
+	}
+	return sum
+}
+
+// addBasis adds amount times the (u, v) DCT basis function to a domain block
+func addBasis(domain []float64, bx, by, u, v int, amount float64) {
+	for y := 0; y < watermarkBlock; y++ {
+		row := (by*watermarkBlock + y) * watermarkDomain
+		for x := 0; x < watermarkBlock; x++ {
+			domain[row+bx*watermarkBlock+x] += amount * dctBasis[u][x] * dctBasis[v][y]
+		}
+	}
+}
+
+// qimQuantize moves a coefficient to the nearest point of the lattice for a bit:
+// multiples of step for 0, offset by half a step for 1
+func qimQuantize(coefficient float64, bit byte, step float64) float64 {
+	offset := float64(bit) * step / 2
+	return math.Round((coefficient-offset)/step)*step + offset
+}
+
+// qimVote returns a soft decision from -1 (bit 0) to 1 (bit 1) for a coefficient
+func qimVote(coefficient, step float64) float64 {
+	// Distance to the nearest bit-0 lattice point, as a fraction of half a step
+	distance := math.Abs(coefficient - math.Round(coefficient/step)*step)
+	return 2*distance/(step/2) - 1
+}
+
+// imageLuma returns the luma of an RGBA image as a row-major slice
+func imageLuma(img *image.RGBA) []float64 {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	luma := make([]float64, width*height)
+	for i := range luma {
+		offset := i * 4
+		luma[i] = 0.299*float64(img.Pix[offset]) + 0.587*float64(img.Pix[offset+1]) + 0.114*float64(img.Pix[offset+2])
+	}
+	return luma
+}
+
+// resampleGray resizes a row-major grayscale image with area averaging
+func resampleGray(src []float64, srcWidth, srcHeight, dstWidth, dstHeight int) []float64 {
+	xWeights := areaWeights(srcWidth, dstWidth)
+	yWeights := areaWeights(srcHeight, dstHeight)
+
+	// Resize rows, then columns
+	rows := make([]float64, dstWidth*srcHeight)
+	for y := 0; y < srcHeight; y++ {
+		for x, weights := range xWeights {
+			sum := 0.0
+			for _, w := range weights {
+				sum += src[y*srcWidth+w.index] * w.weight
+			}
+			rows[y*dstWidth+x] = sum
+		}
+	}
+
+	dst := make([]float64, dstWidth*dstHeight)
+	for y, weights := range yWeights {
+		for x := 0; x < dstWidth; x++ {
+			sum := 0.0
+			for _, w := range weights {
+				sum += rows[w.index*dstWidth+x] * w.weight
+			}
+			dst[y*dstWidth+x] = sum
+		}
+	}
+
+	return dst
+}
+
+// areaWeight is the share of a source sample in a destination sample
+type areaWeight struct {
+	index  int
+	weight float64
+}
+
+// areaWeights returns, for each destination sample, the source samples it covers
+// and their overlap weights, normalized to sum to one
+func areaWeights(srcSize, dstSize int) [][]areaWeight {
+	scale := float64(srcSize) / float64(dstSize)
+	weights := make([][]areaWeight, dstSize)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < srcSize && float64(j) < end; j++ {
+			overlap := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if overlap > 0 {
+				weights[i] = append(weights[i], areaWeight{index: j, weight: overlap / scale})
+			}
+		}
+	}
+	return weights
+}
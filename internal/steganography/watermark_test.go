@@ -0,0 +1,86 @@
+package steganography
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// syntheticPhoto returns an opaque image with smooth gradients, edges and mild
+// noise, standing in for a photograph
+func syntheticPhoto(width, height int, seed int64) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+			base := 120 + 60*math.Sin(6*fx+2*fy) + 40*math.Cos(9*fy-3*fx)
+			if (x/64+y/48)%3 == 0 {
+				base += 30 // Blocky edges
+			}
+			noise := rng.NormFloat64() * 4
+			img.Set(x, y, color.RGBA{
+				clampByte(base + noise),
+				clampByte(base*0.8 + 20 + noise),
+				clampByte(base*0.6 + 50 + noise),
+				0xFF,
+			})
+		}
+	}
+	return img
+}
+
+func clampByte(value float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(value))))
+}
+
+func TestWatermarkSurvivesStandardAttacks(t *testing.T) {
+	encoder, err := NewWatermarkEncoder("watermark-test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := []byte("owner-2024-0042")
+
+	for _, size := range []image.Point{{512, 512}, {640, 560}} {
+		results, err := encoder.TestRobustness(syntheticPhoto(size.X, size.Y, 7), id, StandardWatermarkAttacks())
+		if err != nil {
+			t.Fatalf("%v: TestRobustness: %v", size, err)
+		}
+		if len(results) != len(StandardWatermarkAttacks()) {
+			t.Fatalf("%v: got %d results", size, len(results))
+		}
+		for _, result := range results {
+			if !result.Recovered {
+				t.Errorf("%v: %s: watermark lost (bit error rate %.3f)", size, result.Attack, result.BitErrorRate)
+			}
+		}
+	}
+}
+
+func TestWatermarkRejectsSmallCovers(t *testing.T) {
+	encoder, err := NewWatermarkEncoder("watermark-test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []image.Point{{300, 200}, {511, 800}, {800, 511}} {
+		if _, err := encoder.EmbedImage(syntheticPhoto(size.X, size.Y, 1), []byte("id")); err == nil {
+			t.Errorf("%v: EmbedImage accepted a cover smaller than %dx%d", size, watermarkMinSize, watermarkMinSize)
+		}
+	}
+}
+
+func TestWatermarkWrongSeed(t *testing.T) {
+	encoder, _ := NewWatermarkEncoder("watermark-test", 0)
+	other, _ := NewWatermarkEncoder("other-seed", 0)
+
+	marked, err := encoder.EmbedImage(syntheticPhoto(512, 512, 3), []byte("id"))
+	if err != nil {
+		t.Fatalf("EmbedImage: %v", err)
+	}
+	if id, err := other.ExtractImage(marked); err == nil {
+		t.Errorf("another seed read the watermark %q", id)
+	}
+}
@@ -0,0 +1,138 @@
+// watermarkattack.go - Attack harness for checking watermark robustness
+package steganography
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"math"
+	"strconv"
+)
+
+// WatermarkAttack is an image transformation a robust watermark should survive
+type WatermarkAttack struct {
+	Name  string
+	Apply func(img image.Image) (image.Image, error)
+}
+
+// WatermarkAttackResult is the outcome of reading a watermark after an attack
+type WatermarkAttackResult struct {
+	Attack       string  `json:"attack"`
+	Recovered    bool    `json:"recovered"`    // The ID was read back intact
+	BitErrorRate float64 `json:"bitErrorRate"` // Raw slot errors before voting and the CRC check
+	Width        int     `json:"width"`        // Size of the attacked image
+	Height       int     `json:"height"`
+}
+
+// StandardWatermarkAttacks returns JPEG quality 75, a 50% downscale, and both combined
+func StandardWatermarkAttacks() []WatermarkAttack {
+	jpeg75 := func(img image.Image) (image.Image, error) { return AttackJPEG(img, 75) }
+	half := func(img image.Image) (image.Image, error) { return AttackScale(img, 0.5) }
+
+	return []WatermarkAttack{
+		{Name: "none", Apply: func(img image.Image) (image.Image, error) { return img, nil }},
+		{Name: "jpeg-75", Apply: jpeg75},
+		{Name: "scale-50", Apply: half},
+		{Name: "scale-50+jpeg-75", Apply: func(img image.Image) (image.Image, error) {
+			scaled, err := half(img)
+			if err != nil {
+				return nil, err
+			}
+			return jpeg75(scaled)
+		}},
+	}
+}
+
+// AttackJPEG re-encodes an image as JPEG at the given quality and decodes it again
+func AttackJPEG(img image.Image, quality int) (image.Image, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return jpeg.Decode(&buf)
+}
+
+// AttackScale resizes an image by a factor using area averaging
+func AttackScale(img image.Image, factor float64) (image.Image, error) {
+	rgbaImg := toRGBA(img)
+	width, height := rgbaImg.Bounds().Dx(), rgbaImg.Bounds().Dy()
+	newWidth := int(math.Round(float64(width) * factor))
+	newHeight := int(math.Round(float64(height) * factor))
+	if newWidth < 1 || newHeight < 1 {
+		return nil, errors.New("scale factor " + strconv.FormatFloat(factor, 'g', -1, 64) + " leaves no pixels")
+	}
+
+	output := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	channel := make([]float64, width*height)
+	for c := 0; c < 4; c++ {
+		for i := range channel {
+			channel[i] = float64(rgbaImg.Pix[i*4+c])
+		}
+		resized := resampleGray(channel, width, height, newWidth, newHeight)
+		for i, value := range resized {
+			output.Pix[i*4+c] = uint8(math.Max(0, math.Min(255, math.Round(value))))
+		}
+	}
+
+	return output, nil
+}
+
+// TestRobustness embeds an ID in an image, applies each attack to the watermarked
+// image and reports whether the ID survives and the raw bit error rate
+func (e *WatermarkEncoder) TestRobustness(img image.Image, id []byte, attacks []WatermarkAttack) ([]WatermarkAttackResult, error) {
+	return e.TestRobustnessContext(context.Background(), img, id, attacks, nil)
+}
+
+// TestRobustnessContext is TestRobustness with cancellation and progress reporting
+func (e *WatermarkEncoder) TestRobustnessContext(ctx context.Context, img image.Image, id []byte, attacks []WatermarkAttack, progress Progress) ([]WatermarkAttackResult, error) {
+	t := newTracker(ctx, progress)
+	marked, err := e.embedImage(t.span(0, 0.2), img, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expected slot bits
+	frame := watermarkFrame(id)
+	frameBits := watermarkFrameSize * 8
+
+	var results []WatermarkAttackResult
+	for a, attack := range attacks {
+		span := t.span(0.2+0.8*float64(a)/float64(len(attacks)), 0.2+0.8*float64(a+1)/float64(len(attacks)))
+		if err := span.report(0); err != nil {
+			return nil, err
+		}
+		attacked, err := attack.Apply(marked)
+		if err != nil {
+			return nil, errors.New(attack.Name + ": " + err.Error())
+		}
+
+		result := WatermarkAttackResult{
+			Attack: attack.Name,
+			Width:  attacked.Bounds().Dx(),
+			Height: attacked.Bounds().Dy(),
+		}
+
+		// Count raw slot errors
+		recovered, raw, err := e.readFrame(span, attacked)
+		if err != nil {
+			return nil, err
+		}
+		errorCount := 0
+		for k, bit := range raw {
+			i := k % frameBits
+			if bit != (frame[i/8]>>(7-i%8))&1 {
+				errorCount++
+			}
+		}
+		result.BitErrorRate = float64(errorCount) / float64(len(raw))
+
+		extracted, err := frameID(recovered)
+		result.Recovered = err == nil && bytes.Equal(extracted, id)
+
+		results = append(results, result)
+	}
+
+	return results, t.report(1)
+}
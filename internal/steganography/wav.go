@@ -0,0 +1,458 @@
+// wav.go - RIFF/WAVE, RF64 and Broadcast WAV container handling
+package steganography
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// rf64SizePlaceholder is stored in 32-bit size fields whose real value lives in the ds64 chunk
+const rf64SizePlaceholder = 0xFFFFFFFF
+
+// audioChunk is a chunk preserved from an audio container
+// For the sample data chunk (data/SSND), Data holds only the bytes that precede the samples
+type audioChunk struct {
+	ID   string
+	Data []byte
+}
+
+// audioCarrier holds a parsed PCM audio file
+type audioCarrier struct {
+	Format        string // "wav", "rf64", "bw64", "aiff" or "aifc"
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	BigEndian     bool         // Sample byte order (AIFF is big-endian)
+	Float         bool         // IEEE float samples (WAVE_FORMAT_IEEE_FLOAT)
+	Broadcast     *bextChunk   // Broadcast WAV extension, if present
+	Chunks        []audioChunk // All chunks in file order
+	Samples       []byte       // Raw PCM sample bytes
+}
+
+// isWave reports whether the carrier is a RIFF/WAVE, RF64 or BW64 file
+func (c *audioCarrier) isWave() bool {
+	return c.Format == "wav" || c.Format == "rf64" || c.Format == "bw64"
+}
+
+// is64 reports whether the carrier uses 64-bit sizes from a ds64 chunk (RF64 or BW64)
+func (c *audioCarrier) is64() bool {
+	return c.Format == "rf64" || c.Format == "bw64"
+}
+
+// bytesPerSample returns the storage size of a single sample
+func (c *audioCarrier) bytesPerSample() int {
+	size := (c.BitsPerSample + 7) / 8
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// sampleCount returns the number of individual samples (over all channels)
+func (c *audioCarrier) sampleCount() int {
+	return len(c.Samples) / c.bytesPerSample()
+}
+
+// lsbOffset returns the offset in Samples of the byte holding the LSB of sample i
+func (c *audioCarrier) lsbOffset(i int) int {
+	size := c.bytesPerSample()
+	if c.BigEndian {
+		return i*size + size - 1
+	}
+	return i * size
+}
+
+// sampleValue decodes sample i as a signed integer
+func (c *audioCarrier) sampleValue(i int) int {
+	size := c.bytesPerSample()
+	raw := c.Samples[i*size : i*size+size]
+
+	var value uint32
+	for b := 0; b < size && b < 4; b++ {
+		if c.BigEndian {
+			value |= uint32(raw[size-1-b]) << (8 * b)
+		} else {
+			value |= uint32(raw[b]) << (8 * b)
+		}
+	}
+
+	// 8-bit WAV samples are unsigned; everything else is two's complement
+	if size == 1 && c.isWave() {
+		return int(value) - 128
+	}
+	shift := 32 - 8*min(size, 4)
+	return int(int32(value<<shift) >> shift)
+}
+
+// bextChunk is the Broadcast Wave Format extension chunk (EBU Tech 3285)
+type bextChunk struct {
+	Description          [256]byte
+	Originator           [32]byte
+	OriginatorReference  [32]byte
+	OriginationDate      [10]byte
+	OriginationTime      [8]byte
+	TimeReferenceLow     uint32
+	TimeReferenceHigh    uint32
+	Version              uint16
+	UMID                 [64]byte
+	LoudnessValue        int16
+	LoudnessRange        int16
+	MaxTruePeakLevel     int16
+	MaxMomentaryLoudness int16
+	MaxShortTermLoudness int16
+	Reserved             [180]byte
+	CodingHistory        []byte
+}
+
+// bextFixedSize is the size of the fixed part of the bext chunk
+const bextFixedSize = 602
+
+// parseBextChunk decodes a bext chunk body
+func parseBextChunk(data []byte) (*bextChunk, error) {
+	if len(data) < bextFixedSize {
+		return nil, errors.New("bext chunk is too short")
+	}
+
+	bext := &bextChunk{}
+	r := bytes.NewReader(data[:bextFixedSize])
+	fields := []any{
+		&bext.Description, &bext.Originator, &bext.OriginatorReference,
+		&bext.OriginationDate, &bext.OriginationTime,
+		&bext.TimeReferenceLow, &bext.TimeReferenceHigh, &bext.Version, &bext.UMID,
+		&bext.LoudnessValue, &bext.LoudnessRange, &bext.MaxTruePeakLevel,
+		&bext.MaxMomentaryLoudness, &bext.MaxShortTermLoudness, &bext.Reserved,
+	}
+	for _, field := range fields {
+		if err := binary.Read(r, binary.LittleEndian, field); err != nil {
+			return nil, err
+		}
+	}
+	bext.CodingHistory = append([]byte(nil), data[bextFixedSize:]...)
+
+	return bext, nil
+}
+
+// bytes encodes the bext chunk body
+func (b *bextChunk) bytes() []byte {
+	var buf bytes.Buffer
+	fields := []any{
+		b.Description, b.Originator, b.OriginatorReference,
+		b.OriginationDate, b.OriginationTime,
+		b.TimeReferenceLow, b.TimeReferenceHigh, b.Version, b.UMID,
+		b.LoudnessValue, b.LoudnessRange, b.MaxTruePeakLevel,
+		b.MaxMomentaryLoudness, b.MaxShortTermLoudness, b.Reserved,
+	}
+	for _, field := range fields {
+		binary.Write(&buf, binary.LittleEndian, field)
+	}
+	buf.Write(b.CodingHistory)
+	return buf.Bytes()
+}
+
+// readAudioFile reads a WAV, RF64 or AIFF file based on its magic number
+func readAudioFile(path string) (*audioCarrier, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var magic [4]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil {
+		return nil, errors.New("not a valid audio file")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	switch string(magic[:]) {
+	case "RIFF", "RF64", "BW64":
+		return readWavFile(f)
+	case "FORM":
+		return readAiffFile(f)
+	}
+
+	return nil, errors.New("unsupported audio format: only WAV, RF64 and AIFF are supported")
+}
+
+// writeAudioFile writes an audio carrier in the container format it was read from
+func writeAudioFile(path string, carrier *audioCarrier) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	switch carrier.Format {
+	case "aiff", "aifc":
+		err = writeAiffFile(w, carrier)
+	default:
+		err = writeWavFile(w, carrier)
+	}
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// readWavFile reads a RIFF/WAVE or RF64/BW64 file and preserves all of its chunks
+func readWavFile(f io.Reader) (*audioCarrier, error) {
+	// Read RIFF header
+	var riffID [4]byte
+	var fileSize uint32
+	var waveID [4]byte
+
+	if err := binary.Read(f, binary.LittleEndian, &riffID); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(f, binary.LittleEndian, &fileSize); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(f, binary.LittleEndian, &waveID); err != nil {
+		return nil, err
+	}
+	if string(waveID[:]) != "WAVE" {
+		return nil, errors.New("not a valid WAV file")
+	}
+
+	carrier := &audioCarrier{Format: "wav"}
+	switch string(riffID[:]) {
+	case "RF64":
+		carrier.Format = "rf64"
+	case "BW64":
+		carrier.Format = "bw64"
+	}
+
+	// 64-bit sizes from the ds64 chunk
+	var dataSize64 uint64
+	sizeTable := make(map[string]uint64)
+
+	var fmtFound, dataFound bool
+	for {
+		var chunkID [4]byte
+		var chunkSize uint32
+
+		if err := binary.Read(f, binary.LittleEndian, &chunkID); err != nil {
+			if dataFound {
+				break // End of file, or trailing garbage after the last chunk
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, errors.New("WAV file has no data chunk")
+			}
+			return nil, err
+		}
+		if err := binary.Read(f, binary.LittleEndian, &chunkSize); err != nil {
+			if dataFound {
+				break
+			}
+			return nil, err
+		}
+
+		id := string(chunkID[:])
+		size := uint64(chunkSize)
+		if carrier.is64() && chunkSize == rf64SizePlaceholder {
+			if id == "data" {
+				size = dataSize64
+			} else if tableSize, ok := sizeTable[id]; ok {
+				size = tableSize
+			}
+		}
+
+		body, err := readChunkBody(f, size)
+		if id == "data" {
+			if dataFound {
+				return nil, errors.New("WAV file has more than one data chunk")
+			}
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+			// A truncated data chunk keeps the samples that are present
+			dataFound = true
+			carrier.Samples = body
+			carrier.Chunks = append(carrier.Chunks, audioChunk{ID: id})
+			if err != nil {
+				break
+			}
+		} else {
+			if err != nil {
+				if dataFound {
+					break // Drop a truncated trailing chunk
+				}
+				return nil, err
+			}
+
+			switch id {
+			case "ds64":
+				// Regenerated on write
+				if len(body) < 28 {
+					return nil, errors.New("invalid ds64 chunk")
+				}
+				dataSize64 = binary.LittleEndian.Uint64(body[8:16])
+				tableLength := int(binary.LittleEndian.Uint32(body[24:28]))
+				for i := 0; i < tableLength && 28+i*12+12 <= len(body); i++ {
+					entry := body[28+i*12:]
+					sizeTable[string(entry[0:4])] = binary.LittleEndian.Uint64(entry[4:12])
+				}
+			case "fmt ":
+				if len(body) < 16 {
+					return nil, errors.New("invalid fmt chunk")
+				}
+				fmtFound = true
+				carrier.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+				carrier.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+				carrier.BitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+
+				// IEEE float, directly or as the sub-format of WAVE_FORMAT_EXTENSIBLE
+				formatTag := binary.LittleEndian.Uint16(body[0:2])
+				if formatTag == 0xFFFE && len(body) >= 26 {
+					formatTag = binary.LittleEndian.Uint16(body[24:26])
+				}
+				carrier.Float = formatTag == 3
+			case "bext":
+				bext, err := parseBextChunk(body)
+				if err != nil {
+					return nil, err
+				}
+				carrier.Broadcast = bext
+				body = nil // Serialized from Broadcast on write
+			}
+
+			if id != "ds64" {
+				carrier.Chunks = append(carrier.Chunks, audioChunk{ID: id, Data: body})
+			}
+		}
+
+		// Skip the pad byte of odd-sized chunks
+		if size%2 == 1 {
+			var pad [1]byte
+			if _, err := io.ReadFull(f, pad[:]); err != nil {
+				break
+			}
+		}
+	}
+
+	if !fmtFound {
+		return nil, errors.New("WAV file has no fmt chunk")
+	}
+
+	return carrier, nil
+}
+
+// readChunkBody reads a chunk body of the given size without trusting the size
+// for the allocation, returning io.ErrUnexpectedEOF with the partial body if the
+// file ends early
+func readChunkBody(r io.Reader, size uint64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(body)) < size {
+		return body, io.ErrUnexpectedEOF
+	}
+	return body, nil
+}
+
+// writeWavFile writes a carrier as RIFF/WAVE, switching to RF64 when the output
+// exceeds the 4 GB limit of 32-bit RIFF sizes. RF64 and BW64 input keep their form.
+// Non-data chunks too large for a 32-bit size get an entry in the ds64 table.
+func writeWavFile(w io.Writer, carrier *audioCarrier) error {
+	// Serialize chunk bodies and compute the total RIFF size
+	bodies := make([][]byte, len(carrier.Chunks))
+	var riffSize uint64 = 4 // WAVE
+	for i, chunk := range carrier.Chunks {
+		switch chunk.ID {
+		case "data":
+			riffSize += 8 + uint64(len(carrier.Samples))
+			if len(carrier.Samples)%2 == 1 {
+				riffSize++
+			}
+			continue
+		case "bext":
+			if carrier.Broadcast != nil {
+				bodies[i] = carrier.Broadcast.bytes()
+			}
+		default:
+			bodies[i] = chunk.Data
+		}
+		riffSize += 8 + uint64(len(bodies[i])) + uint64(len(bodies[i])%2)
+	}
+
+	// Chunks other than data whose size needs the ds64 table
+	var tableIDs []string
+	var tableSizes []uint64
+	for i, chunk := range carrier.Chunks {
+		if chunk.ID != "data" && uint64(len(bodies[i])) >= rf64SizePlaceholder {
+			tableIDs = append(tableIDs, chunk.ID)
+			tableSizes = append(tableSizes, uint64(len(bodies[i])))
+		}
+	}
+	ds64Size := 28 + 12*len(tableIDs)
+
+	rf64 := carrier.is64() || riffSize+8+uint64(ds64Size) > rf64SizePlaceholder
+	if rf64 {
+		riffSize += 8 + uint64(ds64Size) // ds64 chunk
+	}
+
+	// Write RIFF header
+	if rf64 {
+		if carrier.Format == "bw64" {
+			io.WriteString(w, "BW64")
+		} else {
+			io.WriteString(w, "RF64")
+		}
+		binary.Write(w, binary.LittleEndian, uint32(rf64SizePlaceholder))
+	} else {
+		io.WriteString(w, "RIFF")
+		binary.Write(w, binary.LittleEndian, uint32(riffSize))
+	}
+	io.WriteString(w, "WAVE")
+
+	if rf64 {
+		sampleFrames := uint64(0)
+		if carrier.Channels > 0 {
+			sampleFrames = uint64(carrier.sampleCount() / carrier.Channels)
+		}
+		io.WriteString(w, "ds64")
+		binary.Write(w, binary.LittleEndian, uint32(ds64Size))
+		binary.Write(w, binary.LittleEndian, riffSize)
+		binary.Write(w, binary.LittleEndian, uint64(len(carrier.Samples)))
+		binary.Write(w, binary.LittleEndian, sampleFrames)
+		binary.Write(w, binary.LittleEndian, uint32(len(tableIDs)))
+		for i, id := range tableIDs {
+			io.WriteString(w, id)
+			binary.Write(w, binary.LittleEndian, tableSizes[i])
+		}
+	}
+
+	// Write chunks in their original order
+	for i, chunk := range carrier.Chunks {
+		body := bodies[i]
+		if chunk.ID == "data" {
+			body = carrier.Samples
+		}
+
+		io.WriteString(w, chunk.ID)
+		if rf64 && (chunk.ID == "data" || uint64(len(body)) >= rf64SizePlaceholder) {
+			binary.Write(w, binary.LittleEndian, uint32(rf64SizePlaceholder))
+		} else {
+			binary.Write(w, binary.LittleEndian, uint32(len(body)))
+		}
+
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+		if len(body)%2 == 1 {
+			if _, err := w.Write([]byte{0}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+// y4m.go - YUV4MPEG2 raw video reading and writing
+package steganography
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// y4mVideo holds a YUV4MPEG2 stream with its frames in memory
+type y4mVideo struct {
+	Params     []string // Stream header parameters after the signature, in file order
+	Width      int
+	Height     int
+	Chroma     string // Chroma subsampling: "420jpeg", "422", "444", "mono", "420p10", ...
+	SampleSize int    // Bytes per sample: 1, or 2 for high bit depth streams (little-endian)
+	Frames     []y4mFrame
+}
+
+// y4mFrame is a single frame: its header parameters and the raw planar samples
+type y4mFrame struct {
+	Params string // Frame header parameters after "FRAME", including the leading space
+	Data   []byte
+}
+
+// y4mPlane describes one plane of a frame
+type y4mPlane struct {
+	Name   string // "y", "u" or "v"
+	Offset int    // Offset of the plane in the frame data, in samples
+	Count  int    // Number of samples in the plane
+}
+
+// readY4MFile reads a YUV4MPEG2 file
+func readY4MFile(path string) (*y4mVideo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readY4M(file)
+}
+
+// readY4M reads a YUV4MPEG2 stream
+func readY4M(in io.Reader) (*y4mVideo, error) {
+	r := bufio.NewReader(in)
+
+	// Parse the stream header
+	header, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(header, "YUV4MPEG2 ") {
+		return nil, errors.New("not a valid Y4M file")
+	}
+
+	video := &y4mVideo{Chroma: "420jpeg"} // 4:2:0 is the default when C is absent
+	video.Params = strings.Fields(header[len("YUV4MPEG2 "):])
+	for _, param := range video.Params {
+		value := param[1:]
+		switch param[0] {
+		case 'W':
+			video.Width, err = strconv.Atoi(value)
+		case 'H':
+			video.Height, err = strconv.Atoi(value)
+		case 'C':
+			video.Chroma = value
+		}
+		if err != nil {
+			return nil, errors.New("invalid Y4M header parameter: " + param)
+		}
+	}
+	if video.Width <= 0 || video.Height <= 0 {
+		return nil, errors.New("Y4M header is missing the frame size")
+	}
+
+	frameSize, err := video.frameSize()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the frames
+	for {
+		frameHeader, err := r.ReadString('\n')
+		if err == io.EOF && frameHeader == "" {
+			break
+		}
+		if err != nil || !strings.HasPrefix(frameHeader, "FRAME") {
+			return nil, errors.New("invalid Y4M frame header")
+		}
+
+		frame := y4mFrame{
+			Params: strings.TrimSuffix(frameHeader[len("FRAME"):], "\n"),
+			Data:   make([]byte, frameSize),
+		}
+		if _, err := io.ReadFull(r, frame.Data); err != nil {
+			return nil, errors.New("truncated Y4M frame")
+		}
+		video.Frames = append(video.Frames, frame)
+	}
+
+	if len(video.Frames) == 0 {
+		return nil, errors.New("Y4M file contains no frames")
+	}
+
+	return video, nil
+}
+
+// writeY4MFile writes a YUV4MPEG2 file, preserving the stream and frame headers
+func writeY4MFile(path string, video *y4mVideo) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	w.WriteString("YUV4MPEG2 " + strings.Join(video.Params, " ") + "\n")
+	for _, frame := range video.Frames {
+		w.WriteString("FRAME" + frame.Params + "\n")
+		w.Write(frame.Data)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// chromaSize returns the dimensions of the U and V planes, or zero for monochrome streams
+func (v *y4mVideo) chromaSize() (int, int, error) {
+	chroma := v.Chroma
+
+	// High bit depth layouts carry a "p10", "p12" or "p16" suffix
+	v.SampleSize = 1
+	if i := strings.LastIndexByte(chroma, 'p'); i > 0 {
+		if depth, err := strconv.Atoi(chroma[i+1:]); err == nil {
+			if depth <= 8 || depth > 16 {
+				return 0, 0, errors.New("unsupported Y4M bit depth: " + v.Chroma)
+			}
+			v.SampleSize = 2
+			chroma = chroma[:i]
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(chroma, "420"):
+		return (v.Width + 1) / 2, (v.Height + 1) / 2, nil
+	case chroma == "422":
+		return (v.Width + 1) / 2, v.Height, nil
+	case chroma == "411":
+		return (v.Width + 3) / 4, v.Height, nil
+	case chroma == "444":
+		return v.Width, v.Height, nil
+	case chroma == "mono":
+		return 0, 0, nil
+	}
+	return 0, 0, errors.New("unsupported Y4M chroma format: " + v.Chroma)
+}
+
+// planes returns the layout of the Y, U and V planes within a frame
+func (v *y4mVideo) planes() ([]y4mPlane, error) {
+	chromaWidth, chromaHeight, err := v.chromaSize()
+	if err != nil {
+		return nil, err
+	}
+
+	lumaCount := v.Width * v.Height
+	planes := []y4mPlane{{Name: "y", Offset: 0, Count: lumaCount}}
+	if chromaCount := chromaWidth * chromaHeight; chromaCount > 0 {
+		planes = append(planes,
+			y4mPlane{Name: "u", Offset: lumaCount, Count: chromaCount},
+			y4mPlane{Name: "v", Offset: lumaCount + chromaCount, Count: chromaCount},
+		)
+	}
+	return planes, nil
+}
+
+// frameSize returns the size of a frame's sample data in bytes
+func (v *y4mVideo) frameSize() (int, error) {
+	planes, err := v.planes()
+	if err != nil {
+		return 0, err
+	}
+
+	last := planes[len(planes)-1]
+	return (last.Offset + last.Count) * v.SampleSize, nil
+}
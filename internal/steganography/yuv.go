@@ -0,0 +1,244 @@
+// yuv.go - LSB steganography in the Y, U and V planes of Y4M video
+package steganography
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+)
+
+// YUVEncoder handles LSB steganography for YUV4MPEG2 video
+type YUVEncoder struct {
+	Seed     int64
+	Plane    string // Plane to embed into: "y", "u", "v" or "all"
+	BitDepth int    // Number of low bits used per sample (1-4)
+}
+
+// NewYUVEncoder creates a new Y4M video encoder with the given seed, plane and bit depth
+func NewYUVEncoder(seed string, plane string, bitDepth int) (*YUVEncoder, error) {
+	if plane == "" {
+		plane = "y"
+	}
+	if plane != "y" && plane != "u" && plane != "v" && plane != "all" {
+		return nil, errors.New("unknown plane: " + plane)
+	}
+
+	// Validate bit depth
+	if bitDepth == 0 {
+		bitDepth = 1 // Default value
+	}
+	if bitDepth < 1 || bitDepth > 4 {
+		return nil, errors.New("bit depth must be between 1 and 4")
+	}
+
+	return &YUVEncoder{
+		Seed:     parseSeed(seed),
+		Plane:    plane,
+		BitDepth: bitDepth,
+	}, nil
+}
+
+// EncodeData embeds binary data into the selected planes of a Y4M file
+func (e *YUVEncoder) EncodeData(inputPath, outputPath string, data []byte) error {
+	return e.EncodeDataContext(context.Background(), inputPath, outputPath, data, nil)
+}
+
+// EncodeDataContext is EncodeData with cancellation and progress reporting
+func (e *YUVEncoder) EncodeDataContext(ctx context.Context, inputPath, outputPath string, data []byte, progress Progress) error {
+	t := newTracker(ctx, progress)
+
+	// Read the video
+	video, err := readY4MFile(inputPath)
+	if err != nil {
+		return err
+	}
+	if err := t.report(0.1); err != nil {
+		return err
+	}
+
+	samples, err := e.newYUVSamples(video)
+	if err != nil {
+		return err
+	}
+
+	// Calculate capacity (BitDepth bits per sample)
+	capacityBits := samples.total * e.BitDepth
+	messageBits := len(data)*8 + 32 // 32 bits for length
+
+	if messageBits > capacityBits {
+		return errors.New("message exceeds video capacity")
+	}
+
+	// Create full data with length prefix
+	fullData := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(fullData[0:4], uint32(len(data)))
+	copy(fullData[4:], data)
+
+	// Generate bit slot indices based on seed
+	indices, err := generatePixelOrderVid(t.span(0.1, 0.8), capacityBits, messageBits, e.Seed)
+	if err != nil {
+		return err
+	}
+
+	// Embed data
+	if err := t.phase(0.8, 0.9, len(indices)); err != nil {
+		return err
+	}
+	for i, slot := range indices {
+		if err := t.advance(1); err != nil {
+			return err
+		}
+		bit := (fullData[i/8] >> (7 - i%8)) & 1
+		f, offset := samples.locate(slot / e.BitDepth)
+		shift := slot % e.BitDepth
+
+		// Clear the target bit and set it to the message bit
+		frame := video.Frames[f].Data
+		frame[offset] = (frame[offset] &^ (1 << shift)) | bit<<shift
+	}
+
+	// Write the modified video
+	if err := writeY4MFile(outputPath, video); err != nil {
+		return err
+	}
+	return t.report(1)
+}
+
+// Capacity returns the largest payload in bytes that EncodeData can hide in the Y4M file
+func (e *YUVEncoder) Capacity(inputPath string) (int, error) {
+	// Read the video
+	video, err := readY4MFile(inputPath)
+	if err != nil {
+		return 0, err
+	}
+
+	samples, err := e.newYUVSamples(video)
+	if err != nil {
+		return 0, err
+	}
+
+	// BitDepth bits per sample, minus the 32-bit length
+	return max((samples.total*e.BitDepth-32)/8, 0), nil
+}
+
+// DecodeData extracts hidden binary data from the selected planes of a Y4M file
+func (e *YUVEncoder) DecodeData(inputPath string) ([]byte, error) {
+	return e.DecodeDataContext(context.Background(), inputPath, nil)
+}
+
+// DecodeDataContext is DecodeData with cancellation and progress reporting
+func (e *YUVEncoder) DecodeDataContext(ctx context.Context, inputPath string, progress Progress) ([]byte, error) {
+	t := newTracker(ctx, progress)
+
+	// Read the video
+	video, err := readY4MFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.report(0.1); err != nil {
+		return nil, err
+	}
+
+	samples, err := e.newYUVSamples(video)
+	if err != nil {
+		return nil, err
+	}
+
+	capacityBits := samples.total * e.BitDepth
+	if capacityBits < 32 {
+		return nil, errors.New("video is too small to hold data")
+	}
+
+	// readBits extracts the bits stored in the slots chosen for the first len(out) bytes
+	readBits := func(t *tracker, out []byte) error {
+		indices, err := generatePixelOrderVid(t, capacityBits, len(out)*8, e.Seed)
+		if err != nil {
+			return err
+		}
+		for i, slot := range indices {
+			f, offset := samples.locate(slot / e.BitDepth)
+			bit := (video.Frames[f].Data[offset] >> (slot % e.BitDepth)) & 1
+			out[i/8] |= bit << (7 - i%8)
+		}
+		return nil
+	}
+
+	// Extract length first
+	var lengthBytes [4]byte
+	if err := readBits(t.span(0.1, 0.1), lengthBytes[:]); err != nil {
+		return nil, err
+	}
+
+	dataLength := binary.BigEndian.Uint32(lengthBytes[:])
+	if dataLength > uint32((capacityBits-32)/8) {
+		return nil, errors.New("invalid data length")
+	}
+
+	// Extract the full message
+	fullData := make([]byte, 4+dataLength)
+	if err := readBits(t.span(0.1, 1), fullData); err != nil {
+		return nil, err
+	}
+
+	return fullData[4:], t.report(1)
+}
+
+// EncodeMessage is a convenience method that encodes a text message
+func (e *YUVEncoder) EncodeMessage(inputPath, outputPath, message string) error {
+	return e.EncodeData(inputPath, outputPath, []byte(message))
+}
+
+// DecodeMessage is a convenience method that decodes a text message
+func (e *YUVEncoder) DecodeMessage(inputPath string) (string, error) {
+	data, err := e.DecodeData(inputPath)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Helper functions
+
+// yuvSamples maps a logical sample index over the selected planes of every frame
+// to the least significant byte of that sample
+type yuvSamples struct {
+	planes     []y4mPlane
+	perFrame   int // Selected samples per frame
+	sampleSize int
+	total      int
+}
+
+// newYUVSamples creates a sample mapping over the selected planes of a video
+func (e *YUVEncoder) newYUVSamples(video *y4mVideo) (*yuvSamples, error) {
+	planes, err := video.planes()
+	if err != nil {
+		return nil, err
+	}
+
+	samples := &yuvSamples{sampleSize: video.SampleSize}
+	for _, plane := range planes {
+		if e.Plane == "all" || plane.Name == e.Plane {
+			samples.planes = append(samples.planes, plane)
+			samples.perFrame += plane.Count
+		}
+	}
+	if len(samples.planes) == 0 {
+		return nil, errors.New("video has no " + e.Plane + " plane")
+	}
+
+	samples.total = samples.perFrame * len(video.Frames)
+	return samples, nil
+}
+
+// locate returns the frame and byte offset of logical sample i
+// High bit depth samples are little-endian, so the low byte comes first
+func (s *yuvSamples) locate(i int) (int, int) {
+	frame, i := i/s.perFrame, i%s.perFrame
+	for _, plane := range s.planes {
+		if i < plane.Count {
+			return frame, (plane.Offset + i) * s.sampleSize
+		}
+		i -= plane.Count
+	}
+	return frame, 0 // Unreachable for i < total
+}
@@ -69,6 +69,18 @@ document.addEventListener('DOMContentLoaded', function() {
         }
     }
     
+    // Supported audio carrier extensions
+    const supportedAudioExtensions = ['.wav', '.rf64', '.aif', '.aiff', '.aifc'];
+
+    function audioFileExtension(name) {
+        const dot = name.lastIndexOf('.');
+        return dot >= 0 ? name.substring(dot).toLowerCase() : '';
+    }
+
+    function isSupportedAudioFile(name) {
+        return supportedAudioExtensions.includes(audioFileExtension(name));
+    }
+    
     // Handle encode form submission
     const encodeTextForm = document.getElementById("encode-text-audio-form");
     if (encodeTextForm) {
@@ -78,9 +90,9 @@ document.addEventListener('DOMContentLoaded', function() {
             const formData = new FormData(this);
             const audioFile = formData.get("audio");
             
-            // Validate file is a WAV
-            if (!audioFile.name.toLowerCase().endsWith('.wav')) {
-                alert("Only WAV files are supported. Please select a valid WAV file.");
+            // Validate file is a WAV or AIFF
+            if (!isSupportedAudioFile(audioFile.name)) {
+                alert("Only WAV and AIFF files are supported. Please select a valid audio file.");
                 return;
             }
             
@@ -163,7 +175,7 @@ document.addEventListener('DOMContentLoaded', function() {
                 // Create download button
                 const downloadLink = document.createElement("a");
                 downloadLink.href = blobUrl;
-                downloadLink.download = "encoded-audio.wav";
+                downloadLink.download = "encoded-audio" + audioFileExtension(audioFile.name);
                 downloadLink.className = "download-btn";
                 downloadLink.innerText = "Download Encoded Audio";
                 audioContainer.appendChild(downloadLink);
@@ -210,9 +222,9 @@ document.addEventListener('DOMContentLoaded', function() {
             const formData = new FormData(this);
             const audioFile = formData.get("audio");
             
-            // Validate file is a WAV
-            if (!audioFile.name.toLowerCase().endsWith('.wav')) {
-                alert("Only WAV files are supported. Please select a valid WAV file.");
+            // Validate file is a WAV or AIFF
+            if (!isSupportedAudioFile(audioFile.name)) {
+                alert("Only WAV and AIFF files are supported. Please select a valid audio file.");
                 return;
             }
             
@@ -1,25 +1,33 @@
 document.addEventListener('DOMContentLoaded', () => {
 // Tab Management
-const setupTabs = (tabSelector, contentSelector) => {
+const setupTabs = (tabSelector, contentSelector, dataKey) => {
     const tabs = document.querySelectorAll(tabSelector);
-    const contents = document.querySelectorAll(contentSelector);
 
     tabs.forEach(tab => {
         tab.addEventListener('click', () => {
+            // Sub-tabs only switch contents within their own parent tab
+            const scope = dataKey === 'subtab' ? tab.closest('.tab-content') : document;
+
             // Remove active states
-            tabs.forEach(t => t.classList.remove('active'));
-            contents.forEach(c => c.classList.remove('active'));
+            scope.querySelectorAll(tabSelector).forEach(t => t.classList.remove('active'));
+            scope.querySelectorAll(contentSelector).forEach(c => c.classList.remove('active'));
 
             // Add active state to clicked tab
             tab.classList.add('active');
-            document.getElementById(`${tab.dataset.tab}-tab`).classList.add('active');
+            document.getElementById(`${tab.dataset[dataKey]}-tab`).classList.add('active');
         });
     });
 };
 
 // Setup main tabs and sub-tabs
-setupTabs('.tab-btn', '.tab-content');
-setupTabs('.sub-tab-btn', '.sub-tab-content');
+setupTabs('.tab-btn', '.tab-content', 'tab');
+setupTabs('.sub-tab-btn', '.sub-tab-content', 'subtab');
+
+// Returns the lowercase extension of a file name, e.g. ".y4m"
+const videoFileExtension = (name) => {
+    const dot = name.lastIndexOf('.');
+    return dot >= 0 ? name.substring(dot).toLowerCase() : '';
+};
 
 // Form Submission Handler
 const handleFormSubmission = (formId, endpoint) => {
@@ -52,12 +60,28 @@ const handleFormSubmission = (formId, endpoint) => {
             // Process response
             if (endpoint.includes('/encode/')) {
                 const url = URL.createObjectURL(data);
+                const what = endpoint.endsWith('/file') ? 'File' : 'Message';
+                const ext = videoFileExtension(formData.get('video').name) === '.y4m' ? '.y4m' : '.avi';
                 resultContent.innerHTML = `
-                    <p>Message encoded successfully!</p>
-                    <a href="${url}" download="stego_video.avi" class="download-btn">
+                    <p>${what} encoded successfully!</p>
+                    <a href="${url}" download="stego_video${ext}" class="download-btn">
                         Download Video
                     </a>
                 `;
+            } else if (endpoint.endsWith('/file')) {
+                // Rebuild the extracted file from its base64 contents
+                const bytes = Uint8Array.from(atob(data.data.fileData), c => c.charCodeAt(0));
+                const url = URL.createObjectURL(new Blob([bytes]));
+                resultContent.innerHTML = `
+                    <p>File extracted successfully!</p>
+                    <div class="file-info">
+                        <strong>File:</strong> ${data.data.fileName}<br>
+                        <strong>Size:</strong> ${(data.data.fileSize / 1024).toFixed(2)} KB
+                    </div>
+                    <a href="${url}" download="${data.data.fileName}" class="download-btn">
+                        Download File
+                    </a>
+                `;
             } else {
                 resultContent.innerHTML = `
                     <p>Message decoded successfully!</p>
@@ -74,4 +98,6 @@ const handleFormSubmission = (formId, endpoint) => {
 // Setup form submissions
 handleFormSubmission('encode-text-form', '/api/video/encode/text');
 handleFormSubmission('decode-text-form', '/api/video/decode/text');
+handleFormSubmission('encode-file-form', '/api/video/encode/file');
+handleFormSubmission('decode-file-form', '/api/video/decode/file');
 });
\ No newline at end of file